@@ -2,46 +2,106 @@ package main
 
 import (
 	"fmt"
+	"html"
 	"net/http"
-	"os"
-	"sync/atomic"
+	"slices"
+	"strings"
 
-	"github.com/joho/godotenv"
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
 )
 
-func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
-		next.ServeHTTP(w, r)
-	})
+// routeSummary is a row of the table on /admin/metrics
+type routeSummary struct {
+	route        string
+	method       string
+	requests     float64
+	serverErrors float64
+	timed        uint64  // requests since the last restart, only those have a latency
+	seconds      float64 // spent handling the timed requests
 }
 
+// hitsHandler shows a summary of the metrics at GET /metrics
 func (cfg *apiConfig) hitsHandler(w http.ResponseWriter, r *http.Request) {
+	// add up the counters per route
+	var appHits float64
+	summaries := map[string]*routeSummary{}
+	keys := []string{}
+	summary := func(route, method string) *routeSummary {
+		key := route + " " + method
+		if _, ok := summaries[key]; !ok {
+			summaries[key] = &routeSummary{route: route, method: method}
+			keys = append(keys, key)
+		}
+		return summaries[key]
+	}
+	for _, s := range cfg.metrics.requests.Samples() {
+		route, method, code := s.LabelValues[0], s.LabelValues[1], s.LabelValues[2]
+		if route == "/app/" {
+			appHits += s.Value
+		}
+		summary(route, method).requests += s.Value
+		if strings.HasPrefix(code, "5") {
+			summary(route, method).serverErrors += s.Value
+		}
+	}
+	for _, s := range cfg.metrics.requestDuration.Samples() {
+		summary(s.LabelValues[0], s.LabelValues[1]).timed += s.Count
+		summary(s.LabelValues[0], s.LabelValues[1]).seconds += s.Sum
+	}
+	slices.Sort(keys)
+
+	// write response
+	var rows strings.Builder
+	for _, key := range keys {
+		s := summaries[key]
+		latency := "-"
+		if s.timed > 0 {
+			latency = fmt.Sprintf("%.1f ms", 1000*s.seconds/float64(s.timed))
+		}
+		fmt.Fprintf(&rows, "\n\t\t\t\t\t<tr><td>%s</td><td>%s</td><td>%.0f</td><td>%.0f</td><td>%s</td></tr>",
+			html.EscapeString(s.route), html.EscapeString(s.method), s.requests, s.serverErrors, latency)
+	}
 	w.Header().Set("Content-Type", "text/html")
 	body := fmt.Sprintf(`<html>
 			<body>
 				<h1>Welcome, Chirpy Admin</h1>
-				<p>Chirpy has been visited %d times!</p>
+				<p>Chirpy has been visited %.0f times!</p>
+				<table>
+					<tr><th>route</th><th>method</th><th>requests</th><th>5xx</th><th>average latency</th></tr>%s
+				</table>
+				<p>All metrics, in Prometheus format, are at <a href="/metrics">/metrics</a>.</p>
 			</body>
-		</html>`, cfg.fileserverHits.Load())
+		</html>`, appHits, rows.String())
 	w.Write([]byte(body))
 }
 
+// requireDevPlatform writes a 403 and returns false unless PLATFORM is set to dev
+func (cfg *apiConfig) requireDevPlatform(w http.ResponseWriter) bool {
+	if cfg.platform != "dev" {
+		writeError(w, 403, nil, "access only allowed from development environment")
+		return false
+	}
+	return true
+}
+
 func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 	// check if platform in .env is set to dev
-	godotenv.Load()
-	platform := os.Getenv("PLATFORM")
-	if platform != "dev" {
-		writeError(w, 403, nil, "access only allowed from development environment")
+	if !cfg.requireDevPlatform(w) {
 		return
 	}
 
-	// reset fileserverhits
-	cfg.fileserverHits = atomic.Int32{}
+	// reset metrics, in memory and in the database
+	err := cfg.resetMetrics(r.Context()) // metrics.go
+	if err != nil {
+		writeError(w, 500, err, "error resetting metrics")
+		return
+	}
 
-	// reset users
-	err := cfg.db.ResetUsers(r.Context())
+	// reset users. the audit trail is append-only, so it survives the reset, admin included
+	err = cfg.audited(r, staffUser(r).ID, auditReset, uuid.Nil, nil, func(q *database.Queries) error {
+		return q.ResetUsers(r.Context())
+	}) // audit.go
 	if err != nil {
 		writeError(w, 500, err, "error running resetusers query")
 		return
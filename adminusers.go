@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// AdminUser is a user as admins see it, so with email, role and suspension
+type AdminUser struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Email       string     `json:"email"`
+	Handle      string     `json:"handle"`
+	DisplayName string     `json:"display_name"`
+	Bio         string     `json:"bio"`
+	IsChirpyRed bool       `json:"is_chirpy_red"`
+	Role        string     `json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at"`
+}
+
+func newAdminUser(user database.User) AdminUser {
+	adminUser := AdminUser{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		IsChirpyRed: user.IsChirpyRed,
+		Role:        user.Role,
+	}
+	if user.SuspendedAt.Valid {
+		adminUser.SuspendedAt = &user.SuspendedAt.Time
+	}
+	return adminUser
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term, so they are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// requestTargetUser returns the user in the {userID} path value, writing an error and returning false if there is none.
+// Admins cannot target themselves, so they cannot lock themselves out.
+func (cfg *apiConfig) requestTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return database.User{}, false
+	} else if userID == staffUser(r).ID { // roles.go
+		writeError(w, 400, errors.New("own account"), "admins cannot do this to their own account")
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return database.User{}, false
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (cfg *apiConfig) getAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	//query parameters
+	query := r.URL.Query()
+	params := database.ListUsersParams{}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		params.Search = sql.NullString{String: likeEscaper.Replace(q), Valid: true}
+	}
+	if role := query.Get("role"); role != "" {
+		if _, ok := roleRanks[role]; !ok {
+			writeError(w, 400, errors.New("incorrect query parameter"), "role should be 'user', 'moderator' or 'admin'")
+			return
+		}
+		params.Role = sql.NullString{String: role, Valid: true}
+	}
+	switch status := query.Get("status"); status {
+	case "":
+	case "active", "suspended":
+		params.Suspended = sql.NullBool{Bool: status == "suspended", Valid: true}
+	default:
+		writeError(w, 400, errors.New("incorrect query parameter"), "status should be either 'active' or 'suspended'")
+		return
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	params.CursorCreatedAt = page.cursorCreatedAt()
+	params.CursorID = page.cursorID()
+	params.PageSize = page.fetchSize()
+
+	// query DB, newest users first
+	users, err := cfg.db.ListUsers(r.Context(), params)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when listing users")
+		return
+	}
+
+	// write response
+	users, nextCursor := paginate(users, page, func(user database.User) cursor {
+		return cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+	})
+	responseUsers := []AdminUser{}
+	for _, user := range users {
+		responseUsers = append(responseUsers, newAdminUser(user))
+	}
+	writeJSON(w, 200, struct {
+		Users      []AdminUser `json:"users"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+	}{responseUsers, nextCursor})
+}
+
+func (cfg *apiConfig) getAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// query DB
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	stats, err := cfg.db.GetUserProfileStats(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database for user stats")
+		return
+	}
+	sessions, err := cfg.db.CountActiveRefreshTokens(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database for sessions")
+		return
+	}
+	openReports, err := cfg.db.CountOpenReportsAgainstUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database for reports")
+		return
+	}
+
+	// write response
+	writeJSON(w, 200, struct {
+		AdminUser
+		ChirpCount     int64 `json:"chirp_count"`
+		FollowerCount  int64 `json:"follower_count"`
+		FollowingCount int64 `json:"following_count"`
+		ActiveSessions int64 `json:"active_sessions"` // refresh tokens that are neither revoked nor expired
+		OpenReports    int64 `json:"open_reports"`
+	}{newAdminUser(user), stats.ChirpCount, stats.FollowerCount, stats.FollowingCount, sessions, openReports})
+}
+
+// suspendUserHandler suspends the user on POST and lifts the suspension on DELETE
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := cfg.requestTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	// suspending also logs the user out, like the suspend_user moderation action
+	var err error
+	if r.Method == http.MethodPost {
+		err = cfg.audited(r, staffUser(r).ID, auditSuspendUser, target.ID, nil, func(q *database.Queries) error {
+			target, err = q.SuspendUser(r.Context(), target.ID)
+			if err != nil {
+				return err
+			}
+			_, err = q.RevokeRefreshTokensForUser(r.Context(), target.ID)
+			return err
+		}) // audit.go
+	} else {
+		err = cfg.audited(r, staffUser(r).ID, auditUnsuspendUser, target.ID, nil, func(q *database.Queries) error {
+			target, err = q.UnsuspendUser(r.Context(), target.ID)
+			return err
+		})
+	}
+	if err != nil {
+		writeError(w, 500, err, "error querying database when updating suspension")
+		return
+	}
+
+	writeJSON(w, 200, newAdminUser(target))
+}
+
+// logoutUserHandler revokes every refresh token of the user. Access tokens they already have run out within the hour.
+func (cfg *apiConfig) logoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := cfg.requestTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	// update query
+	var revoked int64
+	details := map[string]any{}
+	err := cfg.audited(r, staffUser(r).ID, auditLogoutUser, target.ID, details, func(q *database.Queries) error {
+		var err error
+		revoked, err = q.RevokeRefreshTokensForUser(r.Context(), target.ID)
+		details["revoked_sessions"] = revoked
+		return err
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when revoking refresh tokens")
+		return
+	}
+
+	writeJSON(w, 200, struct {
+		RevokedSessions int64 `json:"revoked_sessions"`
+	}{revoked})
+}
+
+func (cfg *apiConfig) setChirpyRedHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := cfg.requestTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	// read request
+	reqParams := struct {
+		IsChirpyRed *bool `json:"is_chirpy_red"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&reqParams)
+	if err != nil || reqParams.IsChirpyRed == nil {
+		writeError(w, 400, err, "request should have is_chirpy_red set to true or false")
+		return
+	}
+
+	// update query
+	details := map[string]any{"from": target.IsChirpyRed, "to": *reqParams.IsChirpyRed}
+	err = cfg.audited(r, staffUser(r).ID, auditSetChirpyRed, target.ID, details, func(q *database.Queries) error {
+		target, err = q.SetChirpyRed(r.Context(), database.SetChirpyRedParams{ID: target.ID, IsChirpyRed: *reqParams.IsChirpyRed})
+		return err
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when setting chirpy red")
+		return
+	}
+
+	writeJSON(w, 200, newAdminUser(target))
+}
+
+// deleteUserHandler deletes the account and, through the ON DELETE clauses in the schema, everything the user made
+func (cfg *apiConfig) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := cfg.requestTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	// delete query. the images are only removed once the rows are gone for good
+	var attachments []database.ChirpAttachment
+	details := map[string]any{"email": target.Email, "handle": target.Handle.String}
+	err := cfg.audited(r, staffUser(r).ID, auditDeleteUser, target.ID, details, func(q *database.Queries) error {
+		var err error
+		attachments, err = q.GetUserAttachments(r.Context(), target.ID)
+		if err != nil {
+			return err
+		}
+		deleted, err := q.DeleteUser(r.Context(), target.ID)
+		if err == nil && deleted == 0 { // deleted in the meantime
+			err = sql.ErrNoRows
+		}
+		return err
+	})
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when deleting user")
+		return
+	}
+	keys := []string{}
+	for _, a := range attachments {
+		keys = append(keys, a.BlobKey, a.ThumbnailKey)
+	}
+	cfg.deleteBlobs(r.Context(), keys) // attachments.go
+
+	writeJSON(w, 204, nil)
+}
@@ -1,26 +1,286 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"sort"
+	"slices"
 	"time"
 
 	"github.com/dcrauwels/chirpy/internal/auth"
 	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/internal/imageproc"
 	"github.com/dcrauwels/chirpy/strutils"
 	"github.com/google/uuid"
 )
 
+// constants
+const (
+	maxChirpLength            int = 140
+	defaultChirpyRedMaxLength int = 280 // unless CHIRPY_RED_MAX_LENGTH says otherwise
+)
+
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Mentions    []Mention    `json:"mentions"`    // mentions.go
+	Attachments []Attachment `json:"attachments"` // attachments.go
+	InReplyTo   *uuid.UUID   `json:"in_reply_to"`
+	ReplyCount  int64        `json:"reply_count"`
+	LikeCount   int64        `json:"like_count"`
+	LikedByMe   *bool        `json:"liked_by_me,omitempty"` // only set when the request comes with a valid access token
+	Edited      bool         `json:"edited"`
+	Deleted     bool         `json:"deleted"` // deleted chirps are kept as a tombstone with an empty body
+	Hidden      bool         `json:"hidden"`  // hidden by a moderator. only its author still sees the body. see moderation.go
+	// a plain rechirp has no body of its own and embeds the chirp it rechirps.
+	// a quote chirp has a body and embeds the chirp it quotes.
+	RechirpOf   *Chirp `json:"rechirp_of,omitempty"`
+	QuotedChirp *Chirp `json:"quoted_chirp,omitempty"`
+	// the viewer's muted words the chirp contains, so clients can show it collapsed. see mutedwords.go
+	MutedWords []string `json:"muted_words,omitempty"`
+}
+
+// PublicUser is the part of a user that anyone may see, so never their email or password
+type PublicUser struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// buildChirps turns database chirps into response chirps as seen by viewerID, which is uuid.Nil for anonymous requests.
+// Anything that is not stored on the chirp row itself is looked up for all chirps at once, never per chirp.
+func (cfg *apiConfig) buildChirps(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	responseChirps := []Chirp{}
+	if len(chirps) == 0 {
+		return responseChirps, nil
+	}
+
+	// chirps that are rechirped or quoted get embedded, but only one level deep
+	referencedIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf.Valid {
+			referencedIDs = append(referencedIDs, chirp.RechirpOf.UUID)
+		}
+		if chirp.QuotedChirpID.Valid {
+			referencedIDs = append(referencedIDs, chirp.QuotedChirpID.UUID)
+		}
+	}
+	allChirps := slices.Clip(chirps) // clipped so appending never writes into the caller's slice
+	if len(referencedIDs) > 0 {
+		referenced, err := cfg.db.GetChirpsByIDs(ctx, referencedIDs)
+		if err != nil {
+			return nil, err
+		}
+		allChirps = append(allChirps, referenced...)
+	}
+
+	chirpIDs := make([]uuid.UUID, len(allChirps))
+	for i, chirp := range allChirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	// reply counts
+	replyCounts := map[uuid.UUID]int64{}
+	rows, err := cfg.db.CountReplies(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		replyCounts[row.ChirpID] = row.ReplyCount
+	}
+
+	// likes
+	likeStats := map[uuid.UUID]database.GetLikeStatsRow{}
+	likeRows, err := cfg.db.GetLikeStats(ctx, database.GetLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range likeRows {
+		likeStats[row.ChirpID] = row
+	}
+
+	// mentions
+	mentions := map[uuid.UUID][]Mention{}
+	mentionRows, err := cfg.db.GetMentionsForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range mentionRows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], Mention{
+			UserID: row.UserID,
+			Handle: row.Handle.String,
+			Start:  row.StartOffset,
+			End:    row.EndOffset,
+		})
+	}
+
+	// attachments
+	attachments := map[uuid.UUID][]Attachment{}
+	attachmentRows, err := cfg.db.GetAttachmentsForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range attachmentRows {
+		attachments[row.ChirpID] = append(attachments[row.ChirpID], cfg.newAttachment(row))
+	}
+
+	builtChirps := map[uuid.UUID]Chirp{}
+	for _, chirp := range allChirps {
+		responseChirp := Chirp{
+			ID:          chirp.ID,
+			CreatedAt:   chirp.CreatedAt,
+			UpdatedAt:   chirp.UpdatedAt,
+			Body:        chirp.Body,
+			UserID:      chirp.UserID,
+			ReplyCount:  replyCounts[chirp.ID],
+			LikeCount:   likeStats[chirp.ID].LikeCount,
+			Edited:      chirp.EditedAt.Valid,
+			Deleted:     chirp.DeletedAt.Valid,
+			Hidden:      chirp.HiddenAt.Valid,
+			Mentions:    []Mention{},
+			Attachments: []Attachment{},
+		}
+		if m, ok := mentions[chirp.ID]; ok {
+			responseChirp.Mentions = m
+		}
+		if a, ok := attachments[chirp.ID]; ok {
+			responseChirp.Attachments = a
+		}
+		if chirp.HiddenAt.Valid && chirp.UserID != viewerID {
+			responseChirp.Body = ""
+			responseChirp.Mentions = []Mention{}
+			responseChirp.Attachments = []Attachment{}
+		}
+		if chirp.InReplyTo.Valid {
+			responseChirp.InReplyTo = &chirp.InReplyTo.UUID
+		}
+		if viewerID != uuid.Nil {
+			likedByMe := likeStats[chirp.ID].LikedByViewer
+			responseChirp.LikedByMe = &likedByMe
+		}
+		builtChirps[chirp.ID] = responseChirp
+	}
+
+	for _, chirp := range chirps {
+		responseChirp := builtChirps[chirp.ID]
+		if referenced, ok := builtChirps[chirp.RechirpOf.UUID]; chirp.RechirpOf.Valid && ok {
+			responseChirp.RechirpOf = &referenced
+		}
+		if referenced, ok := builtChirps[chirp.QuotedChirpID.UUID]; chirp.QuotedChirpID.Valid && ok {
+			responseChirp.QuotedChirp = &referenced
+		}
+		responseChirps = append(responseChirps, responseChirp)
+	}
+	return responseChirps, nil
+}
+
+func (cfg *apiConfig) buildChirp(ctx context.Context, chirp database.Chirp, viewerID uuid.UUID) (Chirp, error) {
+	responseChirps, err := cfg.buildChirps(ctx, []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return responseChirps[0], nil
+}
+
+func newPublicUser(user database.User) PublicUser {
+	return PublicUser{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+}
+
+// chirpCursor is the pagination key of a chirp in every chirp listing
+func chirpCursor(c database.Chirp) cursor {
+	return cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// chirpPage is a single page of a chirp listing
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// userPage is a single page of a user listing
+type userPage struct {
+	Users      []PublicUser `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// chirpLengthError is returned by cleanChirpBody for chirps that are too long
+type chirpLengthError struct {
+	Length    int
+	MaxLength int
+}
+
+func (e *chirpLengthError) Error() string {
+	return fmt.Sprintf("chirp is %d characters long, the maximum is %d", e.Length, e.MaxLength)
+}
+
+// maxChirpLengthFor returns how long the chirps of a user can be. Chirpy Red users get more room.
+func (cfg *apiConfig) maxChirpLengthFor(user database.User) int {
+	if user.IsChirpyRed {
+		return cfg.chirpyRedMaxLength
+	}
+	return maxChirpLength
+}
+
+// cleanChirpBody runs the checks every chirp body has to pass, both when it is posted and when it is edited.
+// Returns the body to store and, in flag mode, the filtered words to flag it for. See filter.go.
+func (cfg *apiConfig) cleanChirpBody(body string, maxLength int) (string, []string, error) {
+	// 1. chirp length, counted the way people see it. see strutils.ChirpLength
+	body = strutils.NormalizeChirp(body)
+	if length := strutils.ChirpLength(body); length > maxLength {
+		return body, nil, &chirpLengthError{Length: length, MaxLength: maxLength}
+	}
+
+	// 2. filtered words
+	return cfg.filterText(body)
+}
+
+// writeChirpBodyError responds to an error from cleanChirpBody. Chirps that are too long get their length
+// in the response, so clients can show how much has to go.
+func writeChirpBodyError(w http.ResponseWriter, err error) {
+	lengthErr := &chirpLengthError{}
+	if errors.As(err, &lengthErr) {
+		msg := fmt.Sprintf("chirp cannot exceed %d characters", lengthErr.MaxLength)
+		writeJSON(w, 400, struct {
+			errorResponse     // json.go
+			Length        int `json:"length"`
+			MaxLength     int `json:"max_length"`
+		}{newErrorResponse(w, err, msg), lengthErr.Length, lengthErr.MaxLength})
+		return
+	}
+	writeError(w, 400, err, "chirp contains words that are not allowed")
+}
+
+// indexChirp stores everything that is derived from a chirp's body, like its hashtags and mentions.
+// Called whenever a body is written. Failures are only logged, as the chirp itself is already saved by then.
+func (cfg *apiConfig) indexChirp(ctx context.Context, chirp database.Chirp) {
+	err := cfg.indexHashtags(ctx, chirp) // hashtags.go
+	if err != nil {
+		log.Printf("error indexing hashtags of chirp %s: %s", chirp.ID, err)
+	}
+	err = cfg.indexMentions(ctx, chirp) // mentions.go
+	if err != nil {
+		log.Printf("error indexing mentions of chirp %s: %s", chirp.ID, err)
+	}
 }
 
 func readinessHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,12 +289,14 @@ func readinessHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func (cfg *apiConfig) postChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	// define types
-	type requestParameters struct {
-		Body string `json:"body"`
-	}
+// chirpRequest is the body of POST /api/chirps, sent either as JSON or as multipart/form-data with images
+type chirpRequest struct {
+	Body          string     `json:"body"`
+	InReplyTo     *uuid.UUID `json:"in_reply_to"`
+	QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+}
 
+func (cfg *apiConfig) postChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	// retrieve token from request
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -48,49 +310,98 @@ func (cfg *apiConfig) postChirpsHandler(w http.ResponseWriter, r *http.Request)
 		writeError(w, 401, err, "user not authorized")
 		return
 	}
-
-	// constants
-	const maxChirpLength int = 140
-	var invalidWords = [3]string{"kerfuffle", "sharbert", "fornax"} //used as const but cannot use const with arrays
+	// before any attachment is read, so suspended users cannot upload
+	user, ok := cfg.requireActiveUser(w, r, tokenUserID) // moderation.go
+	if !ok {
+		return
+	}
 
 	// receive request
-	decoder := json.NewDecoder(r.Body)
-	rParams := requestParameters{}
-	err = decoder.Decode(&rParams)
-	if err != nil {
-		writeError(w, 400, err, "request has incorrect JSON structure") //json.go
-		return
+	rParams := chirpRequest{}
+	images := []imageproc.Image{}
+	if isMultipart(r) {
+		rParams, images, err = readMultipartChirp(w, r) // attachments.go
+		if err != nil {
+			writeError(w, attachmentErrorStatus(err), err, err.Error())
+			return
+		}
+	} else {
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&rParams)
+		if err != nil {
+			writeError(w, 400, err, "request has incorrect JSON structure") //json.go
+			return
+		}
 	}
 	chirpParams := database.CreateChirpParams{
 		Body:   rParams.Body,
 		UserID: tokenUserID,
 	}
 
+	// replies have to point at an existing chirp that has not been deleted
+	if rParams.InReplyTo != nil {
+		parent, err := cfg.db.GetSingleChirp(r.Context(), *rParams.InReplyTo)
+		if err == sql.ErrNoRows || (err == nil && parent.DeletedAt.Valid) {
+			writeError(w, 400, err, "chirp to reply to not found")
+			return
+		} else if err != nil {
+			writeError(w, 500, err, "error querying database")
+			return
+		}
+		if !cfg.requireNotBlocked(w, r, tokenUserID, parent.UserID) { // blocks.go
+			return
+		}
+		chirpParams.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	// same goes for quotes. quoting a rechirp quotes the original
+	if rParams.QuotedChirpID != nil {
+		quoted, err := cfg.db.GetSingleChirp(r.Context(), *rParams.QuotedChirpID)
+		if err == sql.ErrNoRows || (err == nil && quoted.DeletedAt.Valid) {
+			writeError(w, 400, err, "chirp to quote not found")
+			return
+		} else if err != nil {
+			writeError(w, 500, err, "error querying database")
+			return
+		}
+		if !cfg.requireNotBlocked(w, r, tokenUserID, quoted.UserID) {
+			return
+		}
+		if quoted.RechirpOf.Valid {
+			quoted.ID = quoted.RechirpOf.UUID
+		}
+		chirpParams.QuotedChirpID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
 	// other possible checks
-	// 1. chirp length
-	err = strutils.ChirpLength(chirpParams.Body, maxChirpLength)
+	var flaggedWords []string
+	chirpParams.Body, flaggedWords, err = cfg.cleanChirpBody(chirpParams.Body, cfg.maxChirpLengthFor(user))
 	if err != nil {
-		writeError(w, 400, err, fmt.Sprintf("chirp cannot exceed %d characters", maxChirpLength)) //json.go
+		writeChirpBodyError(w, err)
 		return
 	}
 
-	// clean body
-	chirpParams.Body = strutils.ReplaceWord(chirpParams.Body, invalidWords[:], "****")
-
 	// create chirp
-	chirp, err := cfg.db.CreateChirp(r.Context(), chirpParams)
+	var chirp database.Chirp
+	if len(images) > 0 {
+		chirp, err = cfg.createChirpWithAttachments(r.Context(), chirpParams, images) // attachments.go
+	} else {
+		chirp, err = cfg.db.CreateChirp(r.Context(), chirpParams)
+	}
 	if err != nil {
 		writeError(w, 500, err, "server error creating chirp")
 		return
 	}
+	cfg.indexChirp(r.Context(), chirp)
+	cfg.flagChirp(r.Context(), chirp.ID, flaggedWords) // filter.go
+	cfg.publishChirp(r.Context(), chirp)               // stream.go
+	cfg.notifyChirp(r.Context(), chirp)                // notifications.go
 
 	// write response
-	responseChirp := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	responseChirp, err := cfg.buildChirp(r.Context(), chirp, tokenUserID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
 	}
 	writeJSON(w, 201, responseChirp) //json.go
 }
@@ -106,13 +417,28 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 400, errors.New("incorrect query parameter"), "sort value should be either 'asc' or 'desc'")
 		return
 	}
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	viewerID := cfg.optionalUserID(r) // auth.go
 
 	// query DB
-	var chirps []database.Chirp // need to initialize both of these beforehand because of if/else scoping
-	var err error
+	var chirps []database.Chirp // need to initialize this beforehand because of if/else scoping
 	//no authorID
 	if authorID == "" {
-		chirps, err = cfg.db.GetChirps(r.Context())
+		params := database.GetChirpsParams{
+			ViewerID:        viewerID,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			PageSize:        page.fetchSize(),
+		}
+		if sortMethod == "desc" {
+			chirps, err = cfg.db.GetChirpsDesc(r.Context(), database.GetChirpsDescParams(params))
+		} else {
+			chirps, err = cfg.db.GetChirps(r.Context(), params)
+		}
 		if err != nil {
 			writeError(w, 500, err, "error querying database when getting chirps")
 			return
@@ -123,34 +449,65 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 			writeError(w, 400, err, "invalid author ID provided")
 			return
 		}
-		chirps, err = cfg.db.GetChirpsByID(r.Context(), userID)
-		if err == sql.ErrNoRows {
-			writeError(w, 400, err, "no chirps found for this author ID")
-			return
-		} else if err != nil {
+		params := database.GetChirpsByIDParams{
+			UserID:          userID,
+			ViewerID:        viewerID,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			PageSize:        page.fetchSize(),
+		}
+		if sortMethod == "desc" {
+			chirps, err = cfg.db.GetChirpsByIDDesc(r.Context(), database.GetChirpsByIDDescParams(params))
+		} else {
+			chirps, err = cfg.db.GetChirpsByID(r.Context(), params)
+		}
+		if err != nil {
 			writeError(w, 500, err, "error querying database for chirps by author ID")
 			return
 		}
 	}
 
 	// write response
-	responseChirps := []Chirp{}
-	for _, chirp := range chirps {
-		responseChirps = append(responseChirps, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+	chirps, nextCursor := paginate(chirps, page, chirpCursor)
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, viewerID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.hideMutedWords(r.Context(), responseChirps, viewerID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
 	}
+	writeJSON(w, 200, chirpPage{Chirps: responseChirps, NextCursor: nextCursor}) //json.go
+}
 
-	if sortMethod == "desc" {
-		sort.Slice(responseChirps, func(i, j int) bool {
-			return responseChirps[i].UpdatedAt.After(responseChirps[j].UpdatedAt)
-		})
+// requireVisibleChirp writes a 404 and returns false if the viewer should not see the chirp at all: when either of
+// them blocked the other, when a moderator hid it from everyone but its author or when its author is suspended
+func (cfg *apiConfig) requireVisibleChirp(w http.ResponseWriter, r *http.Request, chirp database.Chirp, viewerID uuid.UUID) bool {
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{UserID: viewerID, OtherUserID: chirp.UserID})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return false
+	}
+	if blocked { // as far as either of them is concerned, the chirp does not exist
+		writeError(w, 404, errors.New("chirp blocked"), "chirp not found")
+		return false
+	}
+	if chirp.HiddenAt.Valid && chirp.UserID != viewerID { // hidden by a moderator, see moderation.go
+		writeError(w, 404, errors.New("chirp hidden"), "chirp not found")
+		return false
+	}
+	author, err := cfg.db.GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return false
 	}
-	writeJSON(w, 200, responseChirps) //json.go
+	if author.SuspendedAt.Valid {
+		writeError(w, 404, errors.New("author suspended"), "chirp not found")
+		return false
+	}
+	return true
 }
 
 func (cfg *apiConfig) getSingleChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -170,16 +527,27 @@ func (cfg *apiConfig) getSingleChirpHandler(w http.ResponseWriter, r *http.Reque
 		writeError(w, 404, err, "chirp not found")
 		return
 	}
+	if chirp.DeletedAt.Valid { // tombstones only show up in threads
+		writeError(w, 404, errors.New("chirp deleted"), "chirp not found")
+		return
+	}
+	viewerID := cfg.optionalUserID(r) // auth.go
+	if !cfg.requireVisibleChirp(w, r, chirp, viewerID) {
+		return
+	}
 
 	// write response
-	responseChirp := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	}
-	writeJSON(w, 200, responseChirp)
+	responseChirps, err := cfg.buildChirps(r.Context(), []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.collapseMutedWords(r.Context(), responseChirps, viewerID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, responseChirps[0])
 }
 
 func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request) {
@@ -208,7 +576,7 @@ func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request
 	// querynomics
 	//check if chirp exists
 	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
-	if err == sql.ErrNoRows {
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
 		writeError(w, 404, err, "chirp not found")
 		return
 	} else if err != nil {
@@ -216,8 +584,9 @@ func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request
 		return
 	} else if chirp.UserID != userID {
 		writeError(w, 403, errors.New("wrong user ID"), "user not authorized to delete chirp")
+		return
 	}
-	//delete query
+	//delete query. this leaves a tombstone behind so replies to the chirp keep their thread
 	delParams := database.DeleteSingleChirpParams{
 		ID:     chirpID,
 		UserID: userID,
@@ -225,7 +594,15 @@ func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request
 	_, err = cfg.db.DeleteSingleChirp(r.Context(), delParams)
 	if err != nil {
 		writeError(w, 500, err, "error querying database")
+		return
 	}
+	err = cfg.deleteAttachments(r.Context(), chirpID) // attachments.go
+	if err != nil {
+		writeError(w, 500, err, "error deleting attachments")
+		return
+	}
+	cfg.publishChirpDeleted(chirp)                                                              // stream.go
+	cfg.audit(r, userID, auditChirpDeleted, chirp.UserID, map[string]any{"chirp_id": chirp.ID}) // audit.go
 
 	// return 204
 	writeJSON(w, 204, nil)
@@ -235,8 +612,11 @@ func (cfg *apiConfig) postUsersHandler(w http.ResponseWriter, r *http.Request) {
 	// receive request
 	decoder := json.NewDecoder(r.Body)
 	params := struct { // anonymous as I'm only using this once
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		Handle      string `json:"handle"` // optional, as are display name and bio
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
 	}{}
 	err := decoder.Decode(&params)
 	if err != nil {
@@ -249,6 +629,20 @@ func (cfg *apiConfig) postUsersHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 400, err, "not a valid email address")
 		return
 	}
+	if params.Handle != "" {
+		if err = strutils.ValidateHandle(params.Handle); err != nil {
+			writeError(w, 400, err, err.Error())
+			return
+		}
+	}
+	if err = strutils.ValidateDisplayName(params.DisplayName); err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	if err = strutils.ValidateBio(params.Bio); err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
 
 	// hash password
 	hashedPassword, err := auth.HashPassword(params.Password)
@@ -261,10 +655,16 @@ func (cfg *apiConfig) postUsersHandler(w http.ResponseWriter, r *http.Request) {
 	queryParams := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Handle:         sql.NullString{String: params.Handle, Valid: params.Handle != ""},
+		DisplayName:    params.DisplayName,
+		Bio:            params.Bio,
 	}
 
 	user, err := cfg.db.CreateUser(r.Context(), queryParams)
-	if err != nil {
+	if isUniqueViolation(err, "users_lower_handle_idx") { // dberrors.go
+		writeError(w, 409, err, "handle already taken")
+		return
+	} else if err != nil {
 		writeError(w, 500, err, "error querying database when creating user")
 		return
 	}
@@ -275,12 +675,18 @@ func (cfg *apiConfig) postUsersHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt   time.Time `json:"created_at"`
 		UpdatedAt   time.Time `json:"updated_at"`
 		Email       string    `json:"email"`
+		Handle      string    `json:"handle"`
+		DisplayName string    `json:"display_name"`
+		Bio         string    `json:"bio"`
 		IsChirpyRed bool      `json:"is_chirpy_red"`
 	}{
 		ID:          user.ID,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
 		IsChirpyRed: user.IsChirpyRed,
 	}
 	writeJSON(w, 201, responseParams)
@@ -304,6 +710,10 @@ func (cfg *apiConfig) putUsersHandler(w http.ResponseWriter, r *http.Request) {
 	reqParams := struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		// profile fields are optional and left alone when missing. an empty handle removes it
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
 	}{}
 	decoder := json.NewDecoder(r.Body)
 	err = decoder.Decode(&reqParams)
@@ -312,24 +722,103 @@ func (cfg *apiConfig) putUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the current profile, and the old email and password for the audit trail
+	previous, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	profileParams := database.UpdateProfileParams{
+		ID:          userID,
+		Handle:      previous.Handle,
+		DisplayName: previous.DisplayName,
+		Bio:         previous.Bio,
+	}
+	updateProfile := reqParams.Handle != nil || reqParams.DisplayName != nil || reqParams.Bio != nil
+	if reqParams.Handle != nil {
+		handle := *reqParams.Handle
+		if handle != "" {
+			if err = strutils.ValidateHandle(handle); err != nil {
+				writeError(w, 400, err, err.Error())
+				return
+			}
+		}
+		profileParams.Handle = sql.NullString{String: handle, Valid: handle != ""}
+	}
+	if reqParams.DisplayName != nil {
+		if err = strutils.ValidateDisplayName(*reqParams.DisplayName); err != nil {
+			writeError(w, 400, err, err.Error())
+			return
+		}
+		profileParams.DisplayName = *reqParams.DisplayName
+	}
+	if reqParams.Bio != nil {
+		if err = strutils.ValidateBio(*reqParams.Bio); err != nil {
+			writeError(w, 400, err, err.Error())
+			return
+		}
+		profileParams.Bio = *reqParams.Bio
+	}
+
 	// hash password
+	passwordChanged := auth.CheckPasswordHash(previous.HashedPassword, reqParams.Password) != nil
 	hashedPassword, err := auth.HashPassword(reqParams.Password)
 	if err != nil {
 		writeError(w, 500, err, "error hashing password")
 		return
 	}
 
+	// profile, email and password in one transaction, so a failure never leaves the user half updated
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeError(w, 500, err, "error starting transaction")
+		return
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+
+	if updateProfile {
+		_, err = qtx.UpdateProfile(r.Context(), profileParams)
+		if isUniqueViolation(err, "users_lower_handle_idx") {
+			writeError(w, 409, err, "handle already taken")
+			return
+		} else if err != nil {
+			writeError(w, 500, err, "error updating profile")
+			return
+		}
+	}
+
 	// run uupdateemailpassword query
 	updateParams := database.UpdateEmailPasswordParams{
 		ID:             userID,
 		Email:          reqParams.Email,
 		HashedPassword: hashedPassword,
 	}
-	updatedUser, err := cfg.db.UpdateEmailPassword(r.Context(), updateParams)
+	updatedUser, err := qtx.UpdateEmailPassword(r.Context(), updateParams)
 	if err != nil {
 		writeError(w, 500, err, "error updating email and password")
 		return
 	}
+	// changes are recorded in the same transaction, so none is made without being audited
+	if previous.Email != updatedUser.Email {
+		err = auditWith(qtx, r, userID, auditEmailChanged, userID, map[string]any{"from": previous.Email, "to": updatedUser.Email}) // audit.go
+		if err != nil {
+			writeError(w, 500, err, "error recording audit event")
+			return
+		}
+	}
+	if passwordChanged {
+		err = auditWith(qtx, r, userID, auditPasswordChanged, userID, nil)
+		if err != nil {
+			writeError(w, 500, err, "error recording audit event")
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		writeError(w, 500, err, "error committing transaction")
+		return
+	}
 
 	// return user values with 200 code
 	updatedUserWithoutPassword := struct {
@@ -337,12 +826,18 @@ func (cfg *apiConfig) putUsersHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt   time.Time `json:"created_at"`
 		UpdatedAt   time.Time `json:"updated_at"`
 		Email       string    `json:"email"`
+		Handle      string    `json:"handle"`
+		DisplayName string    `json:"display_name"`
+		Bio         string    `json:"bio"`
 		IsChirpyRed bool      `json:"is_chirpy_red"`
 	}{
 		ID:          updatedUser.ID,
 		CreatedAt:   updatedUser.CreatedAt,
 		UpdatedAt:   updatedUser.UpdatedAt,
 		Email:       updatedUser.Email,
+		Handle:      updatedUser.Handle.String,
+		DisplayName: updatedUser.DisplayName,
+		Bio:         updatedUser.Bio,
 		IsChirpyRed: updatedUser.IsChirpyRed,
 	}
 	writeJSON(w, 200, updatedUserWithoutPassword)
@@ -365,18 +860,25 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	// check if email present in db
 	user, err := cfg.db.GetUserByEmail(r.Context(), reqParams.Email)
 	if err != nil {
+		cfg.audit(r, uuid.Nil, auditLoginFailed, uuid.Nil, map[string]any{"email": reqParams.Email, "reason": "unknown email"}) // audit.go
 		writeError(w, 401, err, "Incorrect email or password")
 		return
 	}
 	// check if password matches
 	err = auth.CheckPasswordHash(user.HashedPassword, reqParams.Password)
 	if err != nil {
+		cfg.audit(r, uuid.Nil, auditLoginFailed, user.ID, map[string]any{"email": reqParams.Email, "reason": "wrong password"})
 		writeError(w, 401, err, "Incorrect email or password") //  not perfectly DRY but I think the DRY solution would be less legible
 		return
 	}
+	if user.SuspendedAt.Valid { // see moderation.go
+		cfg.audit(r, uuid.Nil, auditLoginFailed, user.ID, map[string]any{"email": reqParams.Email, "reason": "suspended"})
+		writeError(w, 403, errors.New("user suspended"), "account is suspended")
+		return
+	}
 
 	// time to make access token
-	token, err := auth.MakeJWT(user.ID, cfg.secret)
+	token, err := auth.MakeJWTWithRole(user.ID, user.Role, cfg.secret)
 	if err != nil {
 		writeError(w, 500, err, "error creating JWT")
 		return
@@ -400,6 +902,7 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 500, err, "error adding refresh token to database")
 		return
 	}
+	cfg.audit(r, user.ID, auditLoginSucceeded, user.ID, nil)
 
 	// write response
 	respParams := struct {
@@ -407,7 +910,9 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt    time.Time `json:"created_at"`
 		UpdatedAt    time.Time `json:"updated_at"`
 		Email        string    `json:"email"`
+		Handle       string    `json:"handle"`
 		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		Role         string    `json:"role"`
 		Token        string    `json:"token"`
 		RefreshToken string    `json:"refresh_token"`
 	}{
@@ -415,7 +920,9 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
+		Handle:       user.Handle.String,
 		IsChirpyRed:  user.IsChirpyRed,
+		Role:         user.Role,
 		Token:        token,
 		RefreshToken: refreshToken,
 	}
@@ -448,11 +955,23 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// return access token
-	accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.secret)
+	// return access token with the user's current role
+	user, err := cfg.db.GetUserByID(r.Context(), refreshToken.UserID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	if user.SuspendedAt.Valid { // see moderation.go
+		cfg.audit(r, uuid.Nil, auditRefreshFailed, user.ID, map[string]any{"reason": "suspended"}) // audit.go
+		writeError(w, 403, errors.New("user suspended"), "account is suspended")
+		return
+	}
+	accessToken, err := auth.MakeJWTWithRole(user.ID, user.Role, cfg.secret)
 	if err != nil {
 		writeError(w, 500, err, "error creating access token")
+		return
 	}
+	cfg.audit(r, user.ID, auditTokenRefreshed, user.ID, nil) // audit.go
 
 	respParams := struct {
 		Token string `json:"token"`
@@ -476,6 +995,9 @@ func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		writeError(w, 401, err, "refresh token not found in database")
 	}
+	if refreshToken, err := cfg.db.GetRefreshTokenByToken(r.Context(), token); err == nil {
+		cfg.audit(r, refreshToken.UserID, auditTokenRevoked, refreshToken.UserID, nil) // audit.go
+	}
 
 	writeJSON(w, 204, nil)
 }
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/internal/imageproc"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttachments    = 4
+	maxAttachmentSize = 5 << 20 // 5 MiB per image
+	// the whole multipart body: all images plus some room for the other form fields
+	maxMultipartSize = maxAttachments*maxAttachmentSize + 1<<20
+)
+
+var (
+	errAttachmentTooLarge = fmt.Errorf("images cannot exceed %d MB", maxAttachmentSize>>20)
+	errUnsupportedImage   = errors.New("images have to be JPEG, PNG or GIF")
+)
+
+// Attachment is an image attached to a chirp
+type Attachment struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
+}
+
+func (cfg *apiConfig) newAttachment(a database.ChirpAttachment) Attachment {
+	return Attachment{
+		ID:           a.ID,
+		URL:          cfg.blobs.URL(a.BlobKey),
+		ThumbnailURL: cfg.blobs.URL(a.ThumbnailKey),
+		ContentType:  a.ContentType,
+		Width:        a.Width,
+		Height:       a.Height,
+		SizeBytes:    a.SizeBytes,
+	}
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readMultipartChirp reads a chirp posted as multipart/form-data: the same fields as the JSON request
+// as form values, and up to four images as files under "images". The images are checked and processed
+// before anything is stored. Errors are meant for the client, see attachmentErrorStatus for the status code.
+func readMultipartChirp(w http.ResponseWriter, r *http.Request) (chirpRequest, []imageproc.Image, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMultipartSize)
+	err := r.ParseMultipartForm(maxAttachmentSize)
+	if err != nil {
+		return chirpRequest{}, nil, err
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	// form values
+	rParams := chirpRequest{Body: r.FormValue("body")}
+	for field, target := range map[string]**uuid.UUID{
+		"in_reply_to":     &rParams.InReplyTo,
+		"quoted_chirp_id": &rParams.QuotedChirpID,
+	} {
+		if value := r.FormValue(field); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return chirpRequest{}, nil, fmt.Errorf("%s is not a valid uuid", field)
+			}
+			*target = &id
+		}
+	}
+
+	// files
+	files := r.MultipartForm.File["images"]
+	if len(files) > maxAttachments {
+		return chirpRequest{}, nil, fmt.Errorf("a chirp can have at most %d images", maxAttachments)
+	}
+	images := []imageproc.Image{}
+	for _, fileHeader := range files {
+		if fileHeader.Size > maxAttachmentSize {
+			return chirpRequest{}, nil, errAttachmentTooLarge
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return chirpRequest{}, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+		file.Close()
+		if err != nil {
+			return chirpRequest{}, nil, err
+		} else if len(data) > maxAttachmentSize {
+			return chirpRequest{}, nil, errAttachmentTooLarge
+		}
+
+		// the type is sniffed from the content, whatever the client claims it is
+		image, err := imageproc.Process(data)
+		if errors.Is(err, imageproc.ErrUnsupportedType) {
+			return chirpRequest{}, nil, errUnsupportedImage
+		} else if errors.Is(err, imageproc.ErrTooLarge) {
+			return chirpRequest{}, nil, fmt.Errorf("images cannot be larger than %d by %d pixels", imageproc.MaxDimension, imageproc.MaxDimension)
+		} else if err != nil {
+			return chirpRequest{}, nil, fmt.Errorf("%s could not be read as an image", fileHeader.Filename)
+		}
+		images = append(images, image)
+	}
+
+	return rParams, images, nil
+}
+
+func attachmentErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, errAttachmentTooLarge) {
+		return 413
+	}
+	if errors.Is(err, errUnsupportedImage) {
+		return 415
+	}
+	return 400
+}
+
+// storeAttachments writes processed images to the blob store and returns the rows to save for them.
+// When something fails, the blobs that were already written are removed again.
+func (cfg *apiConfig) storeAttachments(ctx context.Context, chirpID uuid.UUID, images []imageproc.Image) ([]database.CreateAttachmentParams, error) {
+	params := []database.CreateAttachmentParams{}
+	keys := []string{}
+	for i, image := range images {
+		id := uuid.New()
+		p := database.CreateAttachmentParams{
+			ID:           id,
+			ChirpID:      chirpID,
+			Position:     int32(i),
+			ContentType:  image.ContentType,
+			SizeBytes:    int64(len(image.Data)),
+			Width:        int32(image.Width),
+			Height:       int32(image.Height),
+			BlobKey:      "attachments/" + id.String() + imageproc.Extension(image.ContentType),
+			ThumbnailKey: "attachments/" + id.String() + "_thumb" + imageproc.Extension(image.ThumbnailContentType),
+		}
+		err := cfg.blobs.Put(ctx, p.BlobKey, bytes.NewReader(image.Data), image.ContentType)
+		if err == nil {
+			err = cfg.blobs.Put(ctx, p.ThumbnailKey, bytes.NewReader(image.Thumbnail), image.ThumbnailContentType)
+		}
+		keys = append(keys, p.BlobKey, p.ThumbnailKey)
+		if err != nil {
+			cfg.deleteBlobs(ctx, keys)
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// deleteBlobs removes stored images. Failures are only logged, leaving an orphaned file at worst.
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		err := cfg.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("error deleting blob %s: %s", key, err)
+		}
+	}
+}
+
+// deleteAttachments removes the attachments of a chirp, both the rows and the images
+func (cfg *apiConfig) deleteAttachments(ctx context.Context, chirpID uuid.UUID) error {
+	deleted, err := cfg.db.DeleteChirpAttachments(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	keys := []string{}
+	for _, a := range deleted {
+		keys = append(keys, a.BlobKey, a.ThumbnailKey)
+	}
+	cfg.deleteBlobs(ctx, keys)
+	return nil
+}
+
+// createChirpWithAttachments stores the images and then saves the chirp along with its attachments in one
+// transaction, so a chirp never shows up with only some of its images.
+func (cfg *apiConfig) createChirpWithAttachments(ctx context.Context, chirpParams database.CreateChirpParams, images []imageproc.Image) (database.Chirp, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+
+	chirp, err := qtx.CreateChirp(ctx, chirpParams)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	attachmentParams, err := cfg.storeAttachments(ctx, chirp.ID, images)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	for _, p := range attachmentParams {
+		_, err = qtx.CreateAttachment(ctx, p)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		keys := []string{}
+		for _, p := range attachmentParams {
+			keys = append(keys, p.BlobKey, p.ThumbnailKey)
+		}
+		cfg.deleteBlobs(ctx, keys)
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// audited events. the audit_events table is append-only, see sql/schema/025_audit_log.sql
+const (
+	// accounts and tokens
+	auditLoginSucceeded  = "login_succeeded"
+	auditLoginFailed     = "login_failed"
+	auditEmailChanged    = "email_changed"
+	auditPasswordChanged = "password_changed"
+	auditTokenRefreshed  = "token_refreshed"
+	auditRefreshFailed   = "token_refresh_failed"
+	auditTokenRevoked    = "token_revoked"
+	auditChirpDeleted    = "chirp_deleted"
+	auditChirpyRedPolka  = "chirpy_red_upgraded"
+	// admin actions
+	auditBootstrapAdmin   = "bootstrap_admin"
+	auditSetRole          = "set_role"
+	auditSuspendUser      = "suspend_user"
+	auditUnsuspendUser    = "unsuspend_user"
+	auditLogoutUser       = "logout_user"
+	auditSetChirpyRed     = "set_chirpy_red"
+	auditDeleteUser       = "delete_user"
+	auditAddFilterWord    = "add_filter_word"
+	auditDeleteFilterWord = "delete_filter_word"
+	auditModerationAction = "moderation_action"
+	auditReset            = "reset"
+)
+
+// exports are read from the database in batches of this size, so they never hold the whole trail in memory
+const auditExportBatchSize = 1000
+
+// AuditEvent is an entry in the audit trail
+type AuditEvent struct {
+	ID           uuid.UUID       `json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	ActorID      *uuid.UUID      `json:"actor_id"`       // null when nobody was logged in, like for failed logins and webhooks
+	Action       string          `json:"action"`         // one of the audit constants
+	TargetUserID *uuid.UUID      `json:"target_user_id"` // the user the event is about
+	IP           string          `json:"ip"`
+	UserAgent    string          `json:"user_agent"`
+	Details      json.RawMessage `json:"details"`
+}
+
+func newAuditEvent(e database.AuditEvent) AuditEvent {
+	event := AuditEvent{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Action:    e.Action,
+		IP:        e.Ip,
+		UserAgent: e.UserAgent,
+		Details:   e.Details,
+	}
+	if e.ActorID.Valid {
+		event.ActorID = &e.ActorID.UUID
+	}
+	if e.TargetUserID.Valid {
+		event.TargetUserID = &e.TargetUserID.UUID
+	}
+	return event
+}
+
+// requestIP is the address the request came from. Chirpy does not sit behind a proxy it trusts, so X-Forwarded-For is ignored.
+func requestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// auditParams describes an event that happens during the request. actorID and targetUserID can be uuid.Nil.
+// details must never contain passwords or tokens.
+func auditParams(r *http.Request, actorID uuid.UUID, action string, targetUserID uuid.UUID, details map[string]any) (database.CreateAuditEventParams, error) {
+	if details == nil {
+		details = map[string]any{}
+	}
+	rawDetails, err := json.Marshal(details)
+	if err != nil {
+		return database.CreateAuditEventParams{}, err
+	}
+	return database.CreateAuditEventParams{
+		ActorID:      uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		Action:       action,
+		TargetUserID: uuid.NullUUID{UUID: targetUserID, Valid: targetUserID != uuid.Nil},
+		Ip:           requestIP(r),
+		UserAgent:    r.UserAgent(),
+		Details:      rawDetails,
+	}, nil
+}
+
+// audit records an event in the audit trail. Like indexChirp, failures are only logged: a user should still be able
+// to log in when the trail cannot be written. Admin actions go through audited instead.
+func (cfg *apiConfig) audit(r *http.Request, actorID uuid.UUID, action string, targetUserID uuid.UUID, details map[string]any) {
+	params, err := auditParams(r, actorID, action, targetUserID, details)
+	if err == nil {
+		_, err = cfg.db.CreateAuditEvent(r.Context(), params)
+	}
+	if err != nil {
+		log.Printf("error recording audit event %s: %s", action, err)
+	}
+}
+
+// audited runs fn in a transaction and writes the action to the audit trail in that same transaction, so an
+// action is never done without being recorded. fn can add to details, which are stored once it is done.
+func (cfg *apiConfig) audited(r *http.Request, actorID uuid.UUID, action string, targetUserID uuid.UUID, details map[string]any, fn func(q *database.Queries) error) error {
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+
+	err = fn(qtx)
+	if err != nil {
+		return err
+	}
+
+	err = auditWith(qtx, r, actorID, action, targetUserID, details)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// auditWith writes an event to the audit trail through q, for handlers that run their own transaction
+func auditWith(q *database.Queries, r *http.Request, actorID uuid.UUID, action string, targetUserID uuid.UUID, details map[string]any) error {
+	params, err := auditParams(r, actorID, action, targetUserID, details)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateAuditEvent(r.Context(), params)
+	return err
+}
+
+// readAuditFilters reads the query parameters GET /admin/audit and its export share
+func readAuditFilters(r *http.Request) (database.GetAuditEventsParams, error) {
+	query := r.URL.Query()
+	params := database.GetAuditEventsParams{}
+	for _, f := range []struct {
+		name string
+		to   *uuid.NullUUID
+	}{{"actor_id", &params.ActorID}, {"target_user_id", &params.TargetUserID}} {
+		if v := query.Get(f.name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return params, errors.New(f.name + " is not a valid uuid")
+			}
+			*f.to = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+	if action := query.Get("action"); action != "" {
+		params.Action.String, params.Action.Valid = action, true
+	}
+	var err error
+	params.Since, err = parseSearchTime(query.Get("since")) // search.go
+	if err != nil {
+		return params, errors.New("since should be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	params.Until, err = parseSearchUntil(query.Get("until"))
+	if err != nil {
+		return params, errors.New("until should be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	return params, nil
+}
+
+func (cfg *apiConfig) getAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	params, err := readAuditFilters(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	params.CursorCreatedAt = page.cursorCreatedAt()
+	params.CursorID = page.cursorID()
+	params.PageSize = page.fetchSize()
+
+	// query DB, newest first
+	events, err := cfg.db.GetAuditEvents(r.Context(), params)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting audit events")
+		return
+	}
+
+	// write response
+	events, nextCursor := paginate(events, page, func(e database.AuditEvent) cursor {
+		return cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+	})
+	responseEvents := []AuditEvent{}
+	for _, event := range events {
+		responseEvents = append(responseEvents, newAuditEvent(event))
+	}
+	writeJSON(w, 200, struct {
+		Events     []AuditEvent `json:"events"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}{responseEvents, nextCursor})
+}
+
+// eachAuditEvent calls fn for every event matching params, newest first, reading them in batches
+func (cfg *apiConfig) eachAuditEvent(ctx context.Context, params database.GetAuditEventsParams, fn func(database.AuditEvent) error) error {
+	params.PageSize = auditExportBatchSize
+	for {
+		events, err := cfg.db.GetAuditEvents(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(events) < auditExportBatchSize {
+			return nil
+		}
+		last := events[len(events)-1]
+		params.CursorCreatedAt.Time, params.CursorCreatedAt.Valid = last.CreatedAt, true
+		params.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+}
+
+// exportAuditEventsHandler streams every matching event as a CSV or JSON download
+func (cfg *apiConfig) exportAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	params, err := readAuditFilters(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	} else if format != "csv" && format != "json" {
+		writeError(w, 400, errors.New("incorrect query parameter"), "format should be either 'csv' or 'json'")
+		return
+	}
+
+	// check the query works before the headers go out, after that errors can only cut the download short
+	_, err = cfg.db.GetAuditEvents(r.Context(), database.GetAuditEventsParams{PageSize: 1})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when exporting audit events")
+		return
+	}
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// write response
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("["))
+		first := true
+		err = cfg.eachAuditEvent(r.Context(), params, func(e database.AuditEvent) error {
+			dat, err := json.Marshal(newAuditEvent(e))
+			if err != nil {
+				return err
+			}
+			if !first {
+				w.Write([]byte(","))
+			}
+			first = false
+			_, err = w.Write(dat)
+			return err
+		})
+		w.Write([]byte("]"))
+	} else {
+		w.Header().Set("Content-Type", "text/csv")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "created_at", "actor_id", "action", "target_user_id", "ip", "user_agent", "details"})
+		err = cfg.eachAuditEvent(r.Context(), params, func(e database.AuditEvent) error {
+			return cw.Write([]string{
+				e.ID.String(),
+				e.CreatedAt.UTC().Format(time.RFC3339Nano),
+				nullUUIDString(e.ActorID),
+				e.Action,
+				nullUUIDString(e.TargetUserID),
+				e.Ip,
+				csvSafe(e.UserAgent),
+				string(e.Details),
+			})
+		})
+		cw.Flush()
+	}
+	if err != nil {
+		log.Printf("error exporting audit events: %s", err)
+	}
+}
+
+func nullUUIDString(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
+// csvSafe keeps spreadsheet programs from running a value that clients control, like the user agent, as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// requestUserID reads the bearer token from the request and returns the ID of the user it was issued to
+func (cfg *apiConfig) requestUserID(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.secret)
+}
+
+// optionalUserID is requestUserID for endpoints that also work without logging in.
+// Returns uuid.Nil if there is no valid bearer token on the request.
+func (cfg *apiConfig) optionalUserID(r *http.Request) uuid.UUID {
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// requireNotBlocked writes a 403 and returns false if userID and otherUserID blocked one another,
+// for anything one user does to another: replying, liking, following and the like
+func (cfg *apiConfig) requireNotBlocked(w http.ResponseWriter, r *http.Request, userID, otherUserID uuid.UUID) bool {
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:      userID,
+		OtherUserID: otherUserID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return false
+	} else if blocked {
+		writeError(w, 403, errors.New("blocked"), "user is blocked")
+		return false
+	}
+	return true
+}
+
+// requestOtherUser reads the user ID from the path and checks that the user exists and isn't the one making the request,
+// for blocking and muting
+func (cfg *apiConfig) requestOtherUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (uuid.UUID, bool) {
+	otherUserID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return uuid.Nil, false
+	}
+	if otherUserID == userID {
+		writeError(w, 400, errors.New("self block"), "users cannot block or mute themselves")
+		return uuid.Nil, false
+	}
+	_, err = cfg.db.GetUserByID(r.Context(), otherUserID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return uuid.Nil, false
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return uuid.Nil, false
+	}
+	return otherUserID, true
+}
+
+func (cfg *apiConfig) blockHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	blockedID, ok := cfg.requestOtherUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// block query. blocking someone also ends any following between the two, both ways
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeError(w, 500, err, "error starting transaction")
+		return
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+	created, err := qtx.CreateBlock(r.Context(), database.CreateBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when blocking user")
+		return
+	}
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		UserID:      userID,
+		OtherUserID: blockedID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unfollowing user")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		writeError(w, 500, err, "error committing transaction")
+		return
+	}
+	if created > 0 {
+		cfg.hub.Publish(eventUnfollowed, followChange{FollowerID: userID, FolloweeID: blockedID}) // stream.go
+		cfg.hub.Publish(eventUnfollowed, followChange{FollowerID: blockedID, FolloweeID: userID})
+		cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID, OtherUserID: blockedID})
+	}
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// unblock query. unblocking someone you haven't blocked is a no-op. follows are not restored
+	err = cfg.db.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unblocking user")
+		return
+	}
+	cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID, OtherUserID: blockedID}) // stream.go
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) muteHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	mutedID, ok := cfg.requestOtherUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// mute query. unlike blocking, muting leaves follows alone and the muted user never notices
+	err = cfg.db.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when muting user")
+		return
+	}
+	cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID, OtherUserID: mutedID}) // stream.go
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unmuteHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// unmute query
+	err = cfg.db.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unmuting user")
+		return
+	}
+	cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID, OtherUserID: mutedID}) // stream.go
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) getBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, most recently blocked first
+	rows, err := cfg.db.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting blocked users")
+		return
+	}
+
+	// write response
+	rows, nextCursor := paginate(rows, page, func(row database.GetBlockedUsersRow) cursor {
+		return cursor{CreatedAt: row.BlockedAt, ID: row.User.ID}
+	})
+	users := []PublicUser{}
+	for _, row := range rows {
+		users = append(users, newPublicUser(row.User))
+	}
+	writeJSON(w, 200, userPage{Users: users, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) getMutesHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, most recently muted first
+	rows, err := cfg.db.GetMutedUsers(r.Context(), database.GetMutedUsersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting muted users")
+		return
+	}
+
+	// write response
+	rows, nextCursor := paginate(rows, page, func(row database.GetMutedUsersRow) cursor {
+		return cursor{CreatedAt: row.MutedAt, ID: row.User.ID}
+	})
+	users := []PublicUser{}
+	for _, row := range rows {
+		users = append(users, newPublicUser(row.User))
+	}
+	writeJSON(w, 200, userPage{Users: users, NextCursor: nextCursor})
+}
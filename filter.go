@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/strutils"
+	"github.com/google/uuid"
+)
+
+// filter modes, set with FILTER_MODE
+const (
+	filterModeMask   = "mask"   // replace filtered words with ****, the default
+	filterModeReject = "reject" // refuse the chirp with a 400
+	filterModeFlag   = "flag"   // keep the chirp as it is and flag it for moderation
+)
+
+const maxFilterWordLength = 50
+
+// defaultFilterWords are used when the word list cannot be loaded, so chirpy never starts without a filter
+var defaultFilterWords = []string{"kerfuffle", "sharbert", "fornax"}
+
+var errFilteredWords = errors.New("text contains filtered words")
+
+// FlaggedChirp is a chirp that was flagged for containing filtered words
+type FlaggedChirp struct {
+	Chirp     Chirp     `json:"chirp"`
+	Words     []string  `json:"words"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+// readWordsFile reads a word list with one word per line. Empty lines and lines starting with # are skipped.
+func readWordsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// loadFilter builds the filter from the filter_words table and FILTER_WORDS_FILE, and swaps it in.
+// Requests that are already running keep using the filter they started with.
+func (cfg *apiConfig) loadFilter(ctx context.Context) error {
+	rows, err := cfg.db.GetFilterWords(ctx)
+	if err != nil {
+		return err
+	}
+	words := []string{}
+	for _, row := range rows {
+		words = append(words, row.Word)
+	}
+	if cfg.filterWordsFile != "" {
+		fileWords, err := readWordsFile(cfg.filterWordsFile)
+		if err != nil {
+			return err
+		}
+		words = append(words, fileWords...)
+	}
+	cfg.filter.Store(strutils.NewFilter(words))
+	return nil
+}
+
+// filterText applies the filter to a chirp or message according to FILTER_MODE. It returns the text to store,
+// and in flag mode the filtered words it contains. In reject mode it returns errFilteredWords instead.
+func (cfg *apiConfig) filterText(text string) (string, []string, error) {
+	filter := cfg.filter.Load()
+	if cfg.filterMode == filterModeMask {
+		masked, _ := filter.Mask(text)
+		return masked, nil, nil
+	}
+
+	matches := filter.Find(text)
+	if len(matches) == 0 {
+		return text, nil, nil
+	} else if cfg.filterMode == filterModeReject {
+		return text, nil, errFilteredWords
+	}
+	words := []string{}
+	for _, m := range matches {
+		if !slices.Contains(words, m.Word) {
+			words = append(words, m.Word)
+		}
+	}
+	return text, words, nil
+}
+
+// flagChirp records that a chirp contains filtered words. Like indexChirp, failures are only logged.
+func (cfg *apiConfig) flagChirp(ctx context.Context, chirpID uuid.UUID, words []string) {
+	if len(words) == 0 {
+		return
+	}
+	err := cfg.db.FlagChirp(ctx, database.FlagChirpParams{ChirpID: chirpID, Words: words})
+	if err != nil {
+		log.Printf("error flagging chirp %s: %s", chirpID, err)
+	}
+}
+
+func (cfg *apiConfig) getFilterWordsHandler(w http.ResponseWriter, r *http.Request) {
+	// query DB. words from FILTER_WORDS_FILE are not listed, they are edited in the file
+	rows, err := cfg.db.GetFilterWords(r.Context())
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting filter words")
+		return
+	}
+
+	// write response
+	words := []string{}
+	for _, row := range rows {
+		words = append(words, row.Word)
+	}
+	writeJSON(w, 200, struct {
+		Words []string `json:"words"`
+		Mode  string   `json:"mode"`
+	}{words, cfg.filterMode})
+}
+
+func (cfg *apiConfig) postFilterWordsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	reqParams := struct {
+		Word string `json:"word"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	word := strings.ToLower(strings.TrimSpace(reqParams.Word))
+	if word == "" || strings.ContainsFunc(word, unicode.IsSpace) {
+		writeError(w, 400, errors.New("invalid word"), "word has to be a single word")
+		return
+	} else if utf8.RuneCountInString(word) > maxFilterWordLength {
+		writeError(w, 400, errors.New("word too long"), fmt.Sprintf("word cannot exceed %d characters", maxFilterWordLength))
+		return
+	}
+
+	// insert query, then rebuild the filter so the word is censored right away
+	var created int64
+	err = cfg.audited(r, staffUser(r).ID, auditAddFilterWord, uuid.Nil, map[string]any{"word": word}, func(q *database.Queries) error {
+		created, err = q.AddFilterWord(r.Context(), word)
+		return err
+	}) // audit.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database when adding filter word")
+		return
+	}
+	err = cfg.loadFilter(r.Context())
+	if err != nil {
+		writeError(w, 500, err, "error reloading filter")
+		return
+	}
+
+	respCode := 201
+	if created == 0 { // already on the list
+		respCode = 200
+	}
+	writeJSON(w, respCode, struct {
+		Word string `json:"word"`
+	}{word})
+}
+
+func (cfg *apiConfig) deleteFilterWordHandler(w http.ResponseWriter, r *http.Request) {
+	// delete query
+	word := strings.ToLower(r.PathValue("word"))
+	errNoSuchWord := errors.New("no such word")
+	err := cfg.audited(r, staffUser(r).ID, auditDeleteFilterWord, uuid.Nil, map[string]any{"word": word}, func(q *database.Queries) error {
+		deleted, err := q.DeleteFilterWord(r.Context(), word)
+		if err == nil && deleted == 0 {
+			err = errNoSuchWord
+		}
+		return err
+	})
+	if err == errNoSuchWord {
+		writeError(w, 404, err, "filter word not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when deleting filter word")
+		return
+	}
+	err = cfg.loadFilter(r.Context())
+	if err != nil {
+		writeError(w, 500, err, "error reloading filter")
+		return
+	}
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) getFlaggedChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, most recently flagged first
+	rows, err := cfg.db.GetFlaggedChirps(r.Context(), database.GetFlaggedChirpsParams{
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting flagged chirps")
+		return
+	}
+
+	// write response
+	rows, nextCursor := paginate(rows, page, func(row database.GetFlaggedChirpsRow) cursor {
+		return cursor{CreatedAt: row.FlaggedAt, ID: row.Chirp.ID}
+	})
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, uuid.Nil) // api.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	flagged := []FlaggedChirp{}
+	for i, row := range rows {
+		flagged = append(flagged, FlaggedChirp{Chirp: responseChirps[i], Words: row.Words, FlaggedAt: row.FlaggedAt})
+	}
+	writeJSON(w, 200, struct {
+		Chirps     []FlaggedChirp `json:"chirps"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}{flagged, nextCursor})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	followerID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+	if _, ok := cfg.requireActiveUser(w, r, followerID); !ok { // moderation.go
+		return
+	}
+	if followerID == followeeID {
+		writeError(w, 400, errors.New("self follow"), "users cannot follow themselves")
+		return
+	}
+
+	// check if user to follow exists
+	_, err = cfg.db.GetUserByID(r.Context(), followeeID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	if !cfg.requireNotBlocked(w, r, followerID, followeeID) { // blocks.go
+		return
+	}
+
+	// follow query. following someone twice is a no-op
+	created, err := cfg.db.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when following user")
+		return
+	}
+	if created > 0 {
+		cfg.hub.Publish(eventFollowed, followChange{FollowerID: followerID, FolloweeID: followeeID})
+		cfg.notify(r.Context(), followeeID, followerID, notificationFollow, uuid.NullUUID{}) // notifications.go
+	}
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	followerID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// unfollow query. unfollowing someone you don't follow is a no-op as well
+	err = cfg.db.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unfollowing user")
+		return
+	}
+	cfg.hub.Publish(eventUnfollowed, followChange{FollowerID: followerID, FolloweeID: followeeID}) // stream.go
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB
+	rows, err := cfg.db.GetFollowers(r.Context(), database.GetFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting followers")
+		return
+	}
+
+	// write response
+	rows, nextCursor := paginate(rows, page, func(row database.GetFollowersRow) cursor {
+		return cursor{CreatedAt: row.FollowedAt, ID: row.User.ID}
+	})
+	users := []PublicUser{}
+	for _, row := range rows {
+		users = append(users, newPublicUser(row.User))
+	}
+	writeJSON(w, 200, userPage{Users: users, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB
+	rows, err := cfg.db.GetFollowing(r.Context(), database.GetFollowingParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting followed users")
+		return
+	}
+
+	// write response
+	rows, nextCursor := paginate(rows, page, func(row database.GetFollowingRow) cursor {
+		return cursor{CreatedAt: row.FollowedAt, ID: row.User.ID}
+	})
+	users := []PublicUser{}
+	for _, row := range rows {
+		users = append(users, newPublicUser(row.User))
+	}
+	writeJSON(w, 200, userPage{Users: users, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB. the timeline is always newest first
+	chirps, err := cfg.db.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting timeline")
+		return
+	}
+
+	// write response
+	chirps, nextCursor := paginate(chirps, page, chirpCursor)
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.hideMutedWords(r.Context(), responseChirps, userID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, chirpPage{Chirps: responseChirps, NextCursor: nextCursor})
+}
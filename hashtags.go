@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/strutils"
+)
+
+const (
+	defaultTrendingWindow       = 24 * time.Hour
+	maxTrendingWindow           = 7 * 24 * time.Hour
+	defaultTrendingLimit  int32 = 10
+)
+
+// TrendingHashtag is a hashtag with how often it was used in the trending window
+type TrendingHashtag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+	UserCount  int64  `json:"user_count"`
+}
+
+// indexHashtags replaces the hashtags linked to a chirp with the ones currently in its body
+func (cfg *apiConfig) indexHashtags(ctx context.Context, chirp database.Chirp) error {
+	err := cfg.db.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	for _, tag := range strutils.ExtractHashtags(chirp.Body) {
+		hashtag, err := cfg.db.UpsertHashtag(ctx, tag)
+		if err != nil {
+			return err
+		}
+		err = cfg.db.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			HashtagID: hashtag.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getHashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	tag := strutils.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		writeError(w, 400, errors.New("empty tag"), "no hashtag provided")
+		return
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	viewerID := cfg.optionalUserID(r) // auth.go
+
+	// query DB, newest first
+	chirps, err := cfg.db.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:             tag,
+		ViewerID:        viewerID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting chirps by hashtag")
+		return
+	}
+
+	// write response
+	chirps, nextCursor := paginate(chirps, page, chirpCursor)
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, viewerID) // api.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.hideMutedWords(r.Context(), responseChirps, viewerID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, chirpPage{Chirps: responseChirps, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) trendingHashtagsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	//window is a duration like "1h" or "24h" counting back from now
+	window := defaultTrendingWindow
+	if wq := r.URL.Query().Get("window"); wq != "" {
+		parsed, err := time.ParseDuration(wq)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			writeError(w, 400, err, "window must be a duration between 0 and 168h")
+			return
+		}
+		window = parsed
+	}
+	limit := defaultTrendingLimit
+	if lq := r.URL.Query().Get("limit"); lq != "" {
+		parsed, err := strconv.Atoi(lq)
+		if err != nil || parsed < 1 || parsed > int(maxPageSize) {
+			writeError(w, 400, err, "limit must be a number between 1 and 100")
+			return
+		}
+		limit = int32(parsed)
+	}
+
+	// query DB
+	rows, err := cfg.db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since:   time.Now().Add(-window),
+		MaxTags: limit,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting trending hashtags")
+		return
+	}
+
+	// write response
+	trending := []TrendingHashtag{}
+	for _, row := range rows {
+		trending = append(trending, TrendingHashtag{
+			Tag:        row.Tag,
+			ChirpCount: row.ChirpCount,
+			UserCount:  row.UserCount,
+		})
+	}
+	writeJSON(w, 200, trending)
+}
@@ -61,3 +61,34 @@ func TestJWT(t *testing.T) {
 		t.Errorf(`ValidateJWT(jwt, "zasxzasx") = %v, %v; expected uuid.Nil, err`, wrongID, err)
 	}
 }
+
+func TestJWTRole(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "qqpp1001"
+
+	// role claim comes back out
+	jwt, err := MakeJWTWithRole(userID, "admin", tokenSecret)
+	if err != nil {
+		t.Fatalf(`MakeJWTWithRole(userID, "admin", "qqpp1001") = %s, %v; expected token, nil`, jwt, err)
+	}
+	validatedID, role, err := ValidateJWTRole(jwt, tokenSecret)
+	if err != nil || validatedID != userID || role != "admin" {
+		t.Errorf(`ValidateJWTRole(jwt, "qqpp1001") = %s, %q, %v; expected %s, "admin", nil`, validatedID, role, err, userID)
+	}
+
+	// tokens without a role still validate, with an empty role
+	jwt, err = MakeJWT(userID, tokenSecret)
+	if err != nil {
+		t.Fatalf(`MakeJWT(userID, "qqpp1001") = %s, %v; expected token, nil`, jwt, err)
+	}
+	validatedID, role, err = ValidateJWTRole(jwt, tokenSecret)
+	if err != nil || validatedID != userID || role != "" {
+		t.Errorf(`ValidateJWTRole(jwt, "qqpp1001") = %s, %q, %v; expected %s, "", nil`, validatedID, role, err, userID)
+	}
+
+	// wrong tokenSecret
+	_, role, err = ValidateJWTRole(jwt, "zasxzasx")
+	if err == nil || role != "" {
+		t.Errorf(`ValidateJWTRole(jwt, "zasxzasx") = %q, %v; expected "", err`, role, err)
+	}
+}
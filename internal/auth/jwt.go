@@ -10,20 +10,66 @@ import (
 	"github.com/google/uuid"
 )
 
+// chirpyClaims are the registered claims plus the role of the user at the time the token was made
+type chirpyClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
 func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
+	return MakeJWTWithRole(userID, "", tokenSecret)
+}
+
+// MakeJWTWithRole is MakeJWT with a role claim. The claim is only as fresh as the token, so anything that
+// depends on it should check the role against the database as well.
+func MakeJWTWithRole(userID uuid.UUID, role, tokenSecret string) (string, error) {
 	const expiresIn = time.Hour
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, chirpyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role: role,
 	})
 	return token.SignedString([]byte(tokenSecret))
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTExpiry is ValidateJWT that also returns when the token expires, for connections that outlive a single request
+func ValidateJWTExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
+	claims, err := validateJWTClaims(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("invalid userID in token: %v", err)
+	}
+	return userID, claims.ExpiresAt.Time, nil
+}
+
+// ValidateJWTRole is ValidateJWT that also returns the role claim, which is empty for tokens without one
+func ValidateJWTRole(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+	claims, err := validateJWTClaims(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("invalid userID in token: %v", err)
+	}
+	return userID, claims.Role, nil
+}
+
+func validateJWTClaims(tokenString, tokenSecret string) (*chirpyClaims, error) {
 	// define claims to unpack into and keyfunc
-	claims := &jwt.RegisteredClaims{}
+	claims := &chirpyClaims{}
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		//check if signed with HMAC
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -35,35 +81,31 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	// parse the token
 	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
 	if err != nil {
-		fmt.Println(tokenString)
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	// token checks
 	// check if token is valid
 	if !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
+	}
+	// check if token is expired. tokens without an expiry are not accepted at all
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("token has no expiry")
 	}
-	// check if token is expired
 	if claims.ExpiresAt.Time.Before(time.Now()) {
-		return uuid.Nil, fmt.Errorf("token expired")
+		return nil, fmt.Errorf("token expired")
 	}
 	// check if token is issued in the future
 	if claims.IssuedAt != nil && claims.IssuedAt.Time.After(time.Now()) {
-		return uuid.Nil, fmt.Errorf("token issued in the future")
+		return nil, fmt.Errorf("token issued in the future")
 	}
 	// check if token is issued by the correct issuer
 	if claims.Issuer != "chirpy" {
-		return uuid.Nil, fmt.Errorf("token issued by incorrect issuer")
+		return nil, fmt.Errorf("token issued by incorrect issuer")
 	}
 
-	// get userID from token
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid userID in token: %v", err)
-	}
-	// return userID
-	return userID, nil
+	return claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
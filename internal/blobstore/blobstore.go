@@ -0,0 +1,29 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// Store keeps binary objects, like uploaded images, under a key.
+// Keys are slash separated relative paths such as "attachments/<id>.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can download the object stored under key
+	URL(key string) string
+}
+
+// ErrInvalidKey is returned for keys that are empty, point at the store itself or try to leave it, like "../secret"
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// ValidateKey checks that a key is a clean relative path
+func ValidateKey(key string) error {
+	if key == "" || key == "." || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return ErrInvalidKey
+	}
+	return nil
+}
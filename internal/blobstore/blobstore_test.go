@@ -0,0 +1,36 @@
+package blobstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	cases := []struct {
+		key   string
+		valid bool
+	}{
+		{"attachments/abc.jpg", true},
+		{"abc.jpg", true},
+		{"a/b/c.png", true},
+		{"..hidden", false}, // could be read as leaving the store, so it is refused along with ..
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"attachments/../../secret", false},
+		{"attachments/./abc.jpg", false},
+		{"attachments//abc.jpg", false},
+		{"attachments/", false},
+		{"..", false},
+		{".", false},
+	}
+
+	for _, c := range cases {
+		err := ValidateKey(c.key)
+		if c.valid && err != nil {
+			t.Errorf(`ValidateKey(%q) = %v; expected nil`, c.key, err)
+		} else if !c.valid && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf(`ValidateKey(%q) = %v; expected ErrInvalidKey`, c.key, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: attachments.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createAttachment = `-- name: CreateAttachment :one
+INSERT INTO chirp_attachments (id, created_at, chirp_id, position, content_type, size_bytes, width, height, blob_key, thumbnail_key)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING id, created_at, chirp_id, position, content_type, size_bytes, width, height, blob_key, thumbnail_key
+`
+
+type CreateAttachmentParams struct {
+	ID           uuid.UUID
+	ChirpID      uuid.UUID
+	Position     int32
+	ContentType  string
+	SizeBytes    int64
+	Width        int32
+	Height       int32
+	BlobKey      string
+	ThumbnailKey string
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (ChirpAttachment, error) {
+	row := q.db.QueryRowContext(ctx, createAttachment,
+		arg.ID,
+		arg.ChirpID,
+		arg.Position,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.BlobKey,
+		arg.ThumbnailKey,
+	)
+	var i ChirpAttachment
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ChirpID,
+		&i.Position,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.BlobKey,
+		&i.ThumbnailKey,
+	)
+	return i, err
+}
+
+const deleteChirpAttachments = `-- name: DeleteChirpAttachments :many
+DELETE FROM chirp_attachments
+WHERE chirp_id = $1
+RETURNING id, created_at, chirp_id, position, content_type, size_bytes, width, height, blob_key, thumbnail_key
+`
+
+// returns the deleted rows so their blobs can be removed as well
+func (q *Queries) DeleteChirpAttachments(ctx context.Context, chirpID uuid.UUID) ([]ChirpAttachment, error) {
+	rows, err := q.db.QueryContext(ctx, deleteChirpAttachments, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpAttachment
+	for rows.Next() {
+		var i ChirpAttachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.BlobKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachmentsForChirps = `-- name: GetAttachmentsForChirps :many
+SELECT id, created_at, chirp_id, position, content_type, size_bytes, width, height, blob_key, thumbnail_key FROM chirp_attachments
+WHERE chirp_id = ANY($1::uuid[])
+ORDER BY chirp_id, position
+`
+
+func (q *Queries) GetAttachmentsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpAttachment, error) {
+	rows, err := q.db.QueryContext(ctx, getAttachmentsForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpAttachment
+	for rows.Next() {
+		var i ChirpAttachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.BlobKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAttachments = `-- name: GetUserAttachments :many
+SELECT chirp_attachments.id, chirp_attachments.created_at, chirp_attachments.chirp_id, chirp_attachments.position, chirp_attachments.content_type, chirp_attachments.size_bytes, chirp_attachments.width, chirp_attachments.height, chirp_attachments.blob_key, chirp_attachments.thumbnail_key FROM chirp_attachments
+JOIN chirps ON chirps.id = chirp_attachments.chirp_id
+WHERE chirps.user_id = $1
+`
+
+// every attachment of the user's chirps, so their images can be removed along with them
+func (q *Queries) GetUserAttachments(ctx context.Context, userID uuid.UUID) ([]ChirpAttachment, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAttachments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpAttachment
+	for rows.Next() {
+		var i ChirpAttachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.BlobKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
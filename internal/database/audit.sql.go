@@ -0,0 +1,126 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: audit.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (id, created_at, actor_id, action, target_user_id, ip, user_agent, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING id, created_at, actor_id, action, target_user_id, details, ip, user_agent
+`
+
+type CreateAuditEventParams struct {
+	ActorID      uuid.NullUUID
+	Action       string
+	TargetUserID uuid.NullUUID
+	Ip           string
+	UserAgent    string
+	Details      json.RawMessage
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRowContext(ctx, createAuditEvent,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetUserID,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Details,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ActorID,
+		&i.Action,
+		&i.TargetUserID,
+		&i.Details,
+		&i.Ip,
+		&i.UserAgent,
+	)
+	return i, err
+}
+
+const getAuditEvents = `-- name: GetAuditEvents :many
+SELECT id, created_at, actor_id, action, target_user_id, details, ip, user_agent FROM audit_events
+WHERE ($1::uuid IS NULL OR actor_id = $1::uuid)
+    AND ($2::uuid IS NULL OR target_user_id = $2::uuid)
+    AND ($3::text IS NULL OR action = $3::text)
+    AND ($4::timestamp IS NULL OR created_at >= $4::timestamp)
+    AND ($5::timestamp IS NULL OR created_at < $5::timestamp)
+    AND ($6::timestamp IS NULL
+    OR (created_at, id) < ($6::timestamp, $7::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $8
+`
+
+type GetAuditEventsParams struct {
+	ActorID         uuid.NullUUID
+	TargetUserID    uuid.NullUUID
+	Action          sql.NullString
+	Since           sql.NullTime
+	Until           sql.NullTime
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetAuditEvents(ctx context.Context, arg GetAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getAuditEvents,
+		arg.ActorID,
+		arg.TargetUserID,
+		arg.Action,
+		arg.Since,
+		arg.Until,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEvent
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActorID,
+			&i.Action,
+			&i.TargetUserID,
+			&i.Details,
+			&i.Ip,
+			&i.UserAgent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,330 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: blocks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createBlock = `-- name: CreateBlock :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+`
+
+type CreateBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CreateBlock(ctx context.Context, arg CreateBlockParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createBlock, arg.BlockerID, arg.BlockedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createMute = `-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (muter_id, muted_id) DO NOTHING
+`
+
+type CreateMuteParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) CreateMute(ctx context.Context, arg CreateMuteParams) error {
+	_, err := q.db.ExecContext(ctx, createMute, arg.MuterID, arg.MutedID)
+	return err
+}
+
+const deleteBlock = `-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2
+`
+
+type DeleteBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) DeleteBlock(ctx context.Context, arg DeleteBlockParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBlock, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const deleteMute = `-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2
+`
+
+type DeleteMuteParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) DeleteMute(ctx context.Context, arg DeleteMuteParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMute, arg.MuterID, arg.MutedID)
+	return err
+}
+
+const getBlockedUsers = `-- name: GetBlockedUsers :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.role, users.suspended_at, blocks.created_at AS blocked_at FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = $1
+    AND ($2::timestamp IS NULL
+    OR (blocks.created_at, users.id) < ($2::timestamp, $3::uuid))
+ORDER BY blocks.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type GetBlockedUsersParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type GetBlockedUsersRow struct {
+	User      User
+	BlockedAt time.Time
+}
+
+func (q *Queries) GetBlockedUsers(ctx context.Context, arg GetBlockedUsersParams) ([]GetBlockedUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBlockedUsers,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBlockedUsersRow
+	for rows.Next() {
+		var i GetBlockedUsersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.IsChirpyRed,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+			&i.BlockedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHiddenUserIDs = `-- name: GetHiddenUserIDs :many
+SELECT blocks.blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = $1
+UNION
+SELECT blockers.blocker_id FROM blocks blockers WHERE blockers.blocked_id = $1
+UNION
+SELECT mutes.muted_id FROM mutes WHERE mutes.muter_id = $1
+`
+
+// everyone whose chirps are hidden from the user, for filtering live events
+func (q *Queries) GetHiddenUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getHiddenUserIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMutedUsers = `-- name: GetMutedUsers :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.role, users.suspended_at, mutes.created_at AS muted_at FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = $1
+    AND ($2::timestamp IS NULL
+    OR (mutes.created_at, users.id) < ($2::timestamp, $3::uuid))
+ORDER BY mutes.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type GetMutedUsersParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type GetMutedUsersRow struct {
+	User    User
+	MutedAt time.Time
+}
+
+func (q *Queries) GetMutedUsers(ctx context.Context, arg GetMutedUsersParams) ([]GetMutedUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMutedUsers,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMutedUsersRow
+	for rows.Next() {
+		var i GetMutedUsersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.IsChirpyRed,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+			&i.MutedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isBlocked = `-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = $2)
+        OR (blocker_id = $2 AND blocked_id = $1)
+) AS blocked
+`
+
+type IsBlockedParams struct {
+	UserID      uuid.UUID
+	OtherUserID uuid.UUID
+}
+
+// whether either user blocked the other
+func (q *Queries) IsBlocked(ctx context.Context, arg IsBlockedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlocked, arg.UserID, arg.OtherUserID)
+	var blocked bool
+	err := row.Scan(&blocked)
+	return blocked, err
+}
+
+const isBlockedByAny = `-- name: IsBlockedByAny :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = ANY($2::uuid[]))
+        OR (blocker_id = ANY($2::uuid[]) AND blocked_id = $1)
+) AS blocked
+`
+
+type IsBlockedByAnyParams struct {
+	UserID       uuid.UUID
+	OtherUserIds []uuid.UUID
+}
+
+// whether the user and any of the others blocked one another
+func (q *Queries) IsBlockedByAny(ctx context.Context, arg IsBlockedByAnyParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedByAny, arg.UserID, pq.Array(arg.OtherUserIds))
+	var blocked bool
+	err := row.Scan(&blocked)
+	return blocked, err
+}
+
+const isBlockedInDirectConversation = `-- name: IsBlockedInDirectConversation :one
+SELECT EXISTS (
+    SELECT 1 FROM conversations
+    JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+    JOIN blocks ON (blocks.blocker_id = $1 AND blocks.blocked_id = conversation_participants.user_id)
+        OR (blocks.blocker_id = conversation_participants.user_id AND blocks.blocked_id = $1)
+    WHERE conversations.id = $2
+        AND conversations.direct_key IS NOT NULL
+) AS blocked
+`
+
+type IsBlockedInDirectConversationParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+}
+
+// whether the user and the other participant of a one-to-one conversation blocked one another.
+// always false for group conversations
+func (q *Queries) IsBlockedInDirectConversation(ctx context.Context, arg IsBlockedInDirectConversationParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedInDirectConversation, arg.UserID, arg.ConversationID)
+	var blocked bool
+	err := row.Scan(&blocked)
+	return blocked, err
+}
+
+const isHidden = `-- name: IsHidden :one
+SELECT hidden_from($1::uuid, $2::uuid)::boolean AS hidden
+`
+
+type IsHiddenParams struct {
+	ViewerID uuid.UUID
+	AuthorID uuid.UUID
+}
+
+// whether chirps and notifications from author_id are hidden from viewer_id, see hidden_from in the schema
+func (q *Queries) IsHidden(ctx context.Context, arg IsHiddenParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isHidden, arg.ViewerID, arg.AuthorID)
+	var hidden bool
+	err := row.Scan(&hidden)
+	return hidden, err
+}
@@ -7,29 +7,114 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countReplies = `-- name: CountReplies :many
+SELECT in_reply_to::uuid AS chirp_id, COUNT(*) AS reply_count FROM chirps
+WHERE in_reply_to = ANY($1::uuid[])
+    AND deleted_at IS NULL
+GROUP BY in_reply_to
+`
+
+type CountRepliesRow struct {
+	ChirpID    uuid.UUID
+	ReplyCount int64
+}
+
+func (q *Queries) CountReplies(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countReplies, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesRow
+	for rows.Next() {
+		var i CountRepliesRow
+		if err := rows.Scan(&i.ChirpID, &i.ReplyCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	InReplyTo     uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.QuotedChirpID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2::uuid
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+// a plain rechirp is a chirp without a body of its own. returns no rows if the user already rechirped the chirp
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,14 +122,25 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const deleteSingleChirp = `-- name: DeleteSingleChirp :one
-DELETE FROM chirps
-WHERE id = $1 AND user_id = $2
-RETURNING id, created_at, updated_at, body, user_id
+WITH revisions AS (
+    DELETE FROM chirp_revisions
+    WHERE chirp_id IN (SELECT c.id FROM chirps c WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NULL)
+)
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.user_id = $2 AND chirps.deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at
 `
 
 type DeleteSingleChirpParams struct {
@@ -52,6 +148,8 @@ type DeleteSingleChirpParams struct {
 	UserID uuid.UUID
 }
 
+// chirps are never removed outright but left as a tombstone, so replies to them keep their place in the thread.
+// earlier versions of the chirp go with it.
 func (q *Queries) DeleteSingleChirp(ctx context.Context, arg DeleteSingleChirpParams) (Chirp, error) {
 	row := q.db.QueryRowContext(ctx, deleteSingleChirp, arg.ID, arg.UserID)
 	var i Chirp
@@ -61,17 +159,166 @@ func (q *Queries) DeleteSingleChirp(ctx context.Context, arg DeleteSingleChirpPa
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to, 1 AS depth FROM chirps parent
+    WHERE parent.id = (SELECT child.in_reply_to FROM chirps child WHERE child.id = $1::uuid)
+        AND NOT chirp_hidden_from($2::uuid, parent.user_id, parent.rechirp_of)
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1 FROM chirps parent
+    JOIN ancestors ON parent.id = ancestors.in_reply_to
+    WHERE ancestors.depth < $3::int
+        AND NOT chirp_hidden_from($2::uuid, parent.user_id, parent.rechirp_of)
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, chirps.rechirp_of, chirps.quoted_chirp_id, chirps.hidden_at FROM chirps
+JOIN ancestors ON ancestors.id = chirps.id
+ORDER BY ancestors.depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID
+	ViewerID uuid.UUID
+	MaxDepth int32
+}
+
+// returns the chain of chirps the given chirp replies to, root first.
+// the chain stops below the first chirp hidden from the viewer, like replies do in GetChirpDescendants
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.ViewerID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT reply.id, 1 AS depth FROM chirps reply
+    WHERE reply.in_reply_to = $2::uuid
+        AND NOT chirp_hidden_from($3::uuid, reply.user_id, reply.rechirp_of)
+    UNION ALL
+    SELECT reply.id, descendants.depth + 1 FROM chirps reply
+    JOIN descendants ON reply.in_reply_to = descendants.id
+    WHERE descendants.depth < $4::int
+        AND NOT chirp_hidden_from($3::uuid, reply.user_id, reply.rechirp_of)
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, chirps.rechirp_of, chirps.quoted_chirp_id, chirps.hidden_at FROM chirps
+JOIN descendants ON descendants.id = chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $1
+`
+
+type GetChirpDescendantsParams struct {
+	MaxReplies int32
+	ChirpID    uuid.UUID
+	ViewerID   uuid.UUID
+	MaxDepth   int32
+}
+
+// returns every reply below the given chirp, oldest first so parents come before their replies.
+// replies hidden from the viewer are left out together with everything below them
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants,
+		arg.MaxReplies,
+		arg.ChirpID,
+		arg.ViewerID,
+		arg.MaxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+    AND NOT chirp_hidden_from($1::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsParams struct {
+	ViewerID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +332,12 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -100,13 +353,134 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByID = `-- name: GetChirpsByID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND NOT chirp_hidden_from($2::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND ($3::timestamp IS NULL
+    OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type GetChirpsByIDParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsByID(ctx context.Context, arg GetChirpsByIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByID,
+		arg.UserID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDDesc = `-- name: GetChirpsByIDDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC
+    AND deleted_at IS NULL
+    AND NOT chirp_hidden_from($2::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND ($3::timestamp IS NULL
+    OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type GetChirpsByIDDescParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsByIDDesc(ctx context.Context, arg GetChirpsByIDDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDDesc,
+		arg.UserID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
+WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetChirpsByID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByID, userID)
+func (q *Queries) GetChirpsByIDs(ctx context.Context, chirpIds []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(chirpIds))
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +494,12 @@ func (q *Queries) GetChirpsByID(ctx context.Context, userID uuid.UUID) ([]Chirp,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -134,8 +514,95 @@ func (q *Queries) GetChirpsByID(ctx context.Context, userID uuid.UUID) ([]Chirp,
 	return items, nil
 }
 
+const getChirpsDesc = `-- name: GetChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+    AND NOT chirp_hidden_from($1::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetChirpsDescParams struct {
+	ViewerID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsDesc(ctx context.Context, arg GetChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDesc,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2::uuid AND deleted_at IS NULL
+`
+
+type GetRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
 const getSingleChirp = `-- name: GetSingleChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at FROM chirps
 WHERE id = $1
 `
 
@@ -148,6 +615,111 @@ func (q *Queries) GetSingleChirp(ctx context.Context, id uuid.UUID) (Chirp, erro
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, chirps.rechirp_of, chirps.quoted_chirp_id, chirps.hidden_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = $1
+    AND chirps.deleted_at IS NULL
+    AND NOT chirp_hidden_from($1, chirps.user_id, chirps.rechirp_of)
+    AND chirps.hidden_at IS NULL
+    AND ($2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuotedChirpID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, created_at, replaced_at, chirp_id, body)
+    SELECT gen_random_uuid(), COALESCE(c.edited_at, c.created_at), NOW(), c.id, c.body FROM chirps c
+    WHERE c.id = $2 AND c.user_id = $3 AND c.deleted_at IS NULL
+    FOR UPDATE
+)
+UPDATE chirps
+SET body = $1, edited_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $2 AND chirps.user_id = $3 AND chirps.deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, rechirp_of, quoted_chirp_id, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body   string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// the current body is copied into chirp_revisions in the same statement, so no version is ever lost
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuotedChirpID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -0,0 +1,153 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: filter.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addFilterWord = `-- name: AddFilterWord :execrows
+INSERT INTO filter_words (word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT (word) DO NOTHING
+`
+
+func (q *Queries) AddFilterWord(ctx context.Context, word string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addFilterWord, word)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFilterWord = `-- name: DeleteFilterWord :execrows
+DELETE FROM filter_words
+WHERE word = $1
+`
+
+func (q *Queries) DeleteFilterWord(ctx context.Context, word string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFilterWord, word)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const flagChirp = `-- name: FlagChirp :exec
+INSERT INTO chirp_flags (chirp_id, created_at, words)
+VALUES (
+    $1,
+    NOW(),
+    $2::text[]
+)
+ON CONFLICT (chirp_id) DO UPDATE SET created_at = EXCLUDED.created_at, words = EXCLUDED.words
+`
+
+type FlagChirpParams struct {
+	ChirpID uuid.UUID
+	Words   []string
+}
+
+// flagging a chirp again, like after an edit, replaces the words it was flagged for
+func (q *Queries) FlagChirp(ctx context.Context, arg FlagChirpParams) error {
+	_, err := q.db.ExecContext(ctx, flagChirp, arg.ChirpID, pq.Array(arg.Words))
+	return err
+}
+
+const getFilterWords = `-- name: GetFilterWords :many
+SELECT word, created_at FROM filter_words
+ORDER BY word ASC
+`
+
+func (q *Queries) GetFilterWords(ctx context.Context) ([]FilterWord, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterWords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterWord
+	for rows.Next() {
+		var i FilterWord
+		if err := rows.Scan(&i.Word, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFlaggedChirps = `-- name: GetFlaggedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, chirps.rechirp_of, chirps.quoted_chirp_id, chirps.hidden_at, chirp_flags.words, chirp_flags.created_at AS flagged_at FROM chirp_flags
+JOIN chirps ON chirps.id = chirp_flags.chirp_id
+WHERE chirps.deleted_at IS NULL
+    AND ($1::timestamp IS NULL
+    OR (chirp_flags.created_at, chirps.id) < ($1::timestamp, $2::uuid))
+ORDER BY chirp_flags.created_at DESC, chirps.id DESC
+LIMIT $3
+`
+
+type GetFlaggedChirpsParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type GetFlaggedChirpsRow struct {
+	Chirp     Chirp
+	Words     []string
+	FlaggedAt time.Time
+}
+
+func (q *Queries) GetFlaggedChirps(ctx context.Context, arg GetFlaggedChirpsParams) ([]GetFlaggedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFlaggedChirps, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFlaggedChirpsRow
+	for rows.Next() {
+		var i GetFlaggedChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.EditedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuotedChirpID,
+			&i.Chirp.HiddenAt,
+			pq.Array(&i.Words),
+			&i.FlaggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
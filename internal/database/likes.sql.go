@@ -0,0 +1,92 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: likes.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createLike = `-- name: CreateLike :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING
+`
+
+type CreateLikeParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) CreateLike(ctx context.Context, arg CreateLikeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createLike, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteLike = `-- name: DeleteLike :exec
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2
+`
+
+type DeleteLikeParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteLike(ctx context.Context, arg DeleteLikeParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLike, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const getLikeStats = `-- name: GetLikeStats :many
+SELECT chirp_id, COUNT(*) AS like_count, BOOL_OR(user_id = $1::uuid)::boolean AS liked_by_viewer FROM likes
+WHERE chirp_id = ANY($2::uuid[])
+GROUP BY chirp_id
+`
+
+type GetLikeStatsParams struct {
+	ViewerID uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+type GetLikeStatsRow struct {
+	ChirpID       uuid.UUID
+	LikeCount     int64
+	LikedByViewer bool
+}
+
+// like counts for a batch of chirps, and whether the viewer is one of the likers
+func (q *Queries) GetLikeStats(ctx context.Context, arg GetLikeStatsParams) ([]GetLikeStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLikeStats, arg.ViewerID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLikeStatsRow
+	for rows.Next() {
+		var i GetLikeStatsRow
+		if err := rows.Scan(&i.ChirpID, &i.LikeCount, &i.LikedByViewer); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
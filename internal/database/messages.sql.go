@@ -0,0 +1,406 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: messages.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addConversationParticipant = `-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (conversation_id, user_id) DO NOTHING
+`
+
+type AddConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) AddConversationParticipant(ctx context.Context, arg AddConversationParticipantParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationParticipant, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const countUnreadMessages = `-- name: CountUnreadMessages :many
+SELECT messages.conversation_id, COUNT(*) AS unread_count FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+    AND conversation_participants.user_id = $1
+WHERE messages.conversation_id = ANY($2::uuid[])
+    AND messages.sender_id != $1
+    AND (conversation_participants.last_read_at IS NULL OR messages.created_at > conversation_participants.last_read_at)
+GROUP BY messages.conversation_id
+`
+
+type CountUnreadMessagesParams struct {
+	UserID          uuid.UUID
+	ConversationIds []uuid.UUID
+}
+
+type CountUnreadMessagesRow struct {
+	ConversationID uuid.UUID
+	UnreadCount    int64
+}
+
+// messages from others sent after the user last read the conversation
+func (q *Queries) CountUnreadMessages(ctx context.Context, arg CountUnreadMessagesParams) ([]CountUnreadMessagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countUnreadMessages, arg.UserID, pq.Array(arg.ConversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountUnreadMessagesRow
+	for rows.Next() {
+		var i CountUnreadMessagesRow
+		if err := rows.Scan(&i.ConversationID, &i.UnreadCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createConversation = `-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING id, created_at, updated_at, created_by, direct_key
+`
+
+type CreateConversationParams struct {
+	CreatedBy uuid.NullUUID
+	DirectKey sql.NullString
+}
+
+// returns no rows if there already is a one-to-one conversation with the same direct_key
+func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createConversation, arg.CreatedBy, arg.DirectKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+WITH touched AS (
+    UPDATE conversations SET updated_at = NOW()
+    WHERE conversations.id = $1
+), marked_read AS (
+    UPDATE conversation_participants SET last_read_at = NOW()
+    WHERE conversation_participants.conversation_id = $1
+        AND conversation_participants.user_id = $2
+)
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING id, created_at, conversation_id, sender_id, body
+`
+
+type CreateMessageParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+// also moves the conversation to the top of the list and marks it read for the sender
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createMessage, arg.ConversationID, arg.SenderID, arg.Body)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const getConversation = `-- name: GetConversation :one
+SELECT id, created_at, updated_at, created_by, direct_key FROM conversations
+WHERE id = $1
+`
+
+func (q *Queries) GetConversation(ctx context.Context, id uuid.UUID) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversation, id)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const getConversationByDirectKey = `-- name: GetConversationByDirectKey :one
+SELECT id, created_at, updated_at, created_by, direct_key FROM conversations
+WHERE direct_key = $1
+`
+
+func (q *Queries) GetConversationByDirectKey(ctx context.Context, directKey sql.NullString) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationByDirectKey, directKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const getConversationParticipants = `-- name: GetConversationParticipants :many
+SELECT conversation_participants.conversation_id, conversation_participants.last_read_at, users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.role, users.suspended_at FROM conversation_participants
+JOIN users ON users.id = conversation_participants.user_id
+WHERE conversation_participants.conversation_id = ANY($1::uuid[])
+ORDER BY conversation_participants.conversation_id, conversation_participants.joined_at, users.id
+`
+
+type GetConversationParticipantsRow struct {
+	ConversationID uuid.UUID
+	LastReadAt     sql.NullTime
+	User           User
+}
+
+func (q *Queries) GetConversationParticipants(ctx context.Context, conversationIds []uuid.UUID) ([]GetConversationParticipantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationParticipants, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetConversationParticipantsRow
+	for rows.Next() {
+		var i GetConversationParticipantsRow
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.LastReadAt,
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.IsChirpyRed,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationsForUser = `-- name: GetConversationsForUser :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.direct_key FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = $1
+    AND ($2::timestamp IS NULL
+    OR (conversations.updated_at, conversations.id) < ($2::timestamp, $3::uuid))
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT $4
+`
+
+type GetConversationsForUserParams struct {
+	UserID          uuid.UUID
+	CursorUpdatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetConversationsForUser(ctx context.Context, arg GetConversationsForUserParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationsForUser,
+		arg.UserID,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CreatedBy,
+			&i.DirectKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastMessages = `-- name: GetLastMessages :many
+SELECT DISTINCT ON (conversation_id) id, created_at, conversation_id, sender_id, body FROM messages
+WHERE conversation_id = ANY($1::uuid[])
+ORDER BY conversation_id, created_at DESC, id DESC
+`
+
+func (q *Queries) GetLastMessages(ctx context.Context, conversationIds []uuid.UUID) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getLastMessages, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessages = `-- name: GetMessages :many
+SELECT id, created_at, conversation_id, sender_id, body FROM messages
+WHERE conversation_id = $1
+    AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetMessagesParams struct {
+	ConversationID  uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetMessages(ctx context.Context, arg GetMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getMessages,
+		arg.ConversationID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isConversationParticipant = `-- name: IsConversationParticipant :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_participants
+    WHERE conversation_id = $1 AND user_id = $2
+)
+`
+
+type IsConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) IsConversationParticipant(ctx context.Context, arg IsConversationParticipantParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isConversationParticipant, arg.ConversationID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const markConversationRead = `-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2
+`
+
+type MarkConversationReadParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
+	_, err := q.db.ExecContext(ctx, markConversationRead, arg.ConversationID, arg.UserID)
+	return err
+}
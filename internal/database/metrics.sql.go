@@ -0,0 +1,76 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: metrics.sql
+
+package database
+
+import (
+	"context"
+)
+
+const addRequestCount = `-- name: AddRequestCount :exec
+INSERT INTO request_counts (route, method, status_code, count, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (route, method, status_code)
+DO UPDATE SET count = request_counts.count + EXCLUDED.count, updated_at = NOW()
+`
+
+type AddRequestCountParams struct {
+	Route      string
+	Method     string
+	StatusCode int32
+	Count      int64
+}
+
+func (q *Queries) AddRequestCount(ctx context.Context, arg AddRequestCountParams) error {
+	_, err := q.db.ExecContext(ctx, addRequestCount,
+		arg.Route,
+		arg.Method,
+		arg.StatusCode,
+		arg.Count,
+	)
+	return err
+}
+
+const getRequestCounts = `-- name: GetRequestCounts :many
+SELECT route, method, status_code, count, updated_at FROM request_counts
+`
+
+func (q *Queries) GetRequestCounts(ctx context.Context) ([]RequestCount, error) {
+	rows, err := q.db.QueryContext(ctx, getRequestCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RequestCount
+	for rows.Next() {
+		var i RequestCount
+		if err := rows.Scan(
+			&i.Route,
+			&i.Method,
+			&i.StatusCode,
+			&i.Count,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetRequestCounts = `-- name: ResetRequestCounts :exec
+DELETE FROM request_counts
+`
+
+func (q *Queries) ResetRequestCounts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetRequestCounts)
+	return err
+}
@@ -0,0 +1,365 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: moderation.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const assignReport = `-- name: AssignReport :one
+UPDATE reports
+SET assignee_id = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details, status, assignee_id, resolved_at
+`
+
+type AssignReportParams struct {
+	AssigneeID uuid.NullUUID
+	ID         uuid.UUID
+}
+
+func (q *Queries) AssignReport(ctx context.Context, arg AssignReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, assignReport, arg.AssigneeID, arg.ID)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.ChirpBody,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.AssigneeID,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const createModerationAction = `-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, report_id, user_id, chirp_id, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING id, created_at, moderator_id, action, report_id, user_id, chirp_id, note
+`
+
+type CreateModerationActionParams struct {
+	ModeratorID uuid.NullUUID
+	Action      string
+	ReportID    uuid.NullUUID
+	UserID      uuid.NullUUID
+	ChirpID     uuid.NullUUID
+	Note        string
+}
+
+func (q *Queries) CreateModerationAction(ctx context.Context, arg CreateModerationActionParams) (ModerationAction, error) {
+	row := q.db.QueryRowContext(ctx, createModerationAction,
+		arg.ModeratorID,
+		arg.Action,
+		arg.ReportID,
+		arg.UserID,
+		arg.ChirpID,
+		arg.Note,
+	)
+	var i ModerationAction
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ModeratorID,
+		&i.Action,
+		&i.ReportID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Note,
+	)
+	return i, err
+}
+
+const createReport = `-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details, status, assignee_id, resolved_at
+`
+
+type CreateReportParams struct {
+	ReporterID uuid.UUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	ChirpBody  sql.NullString
+	Reason     string
+	Details    string
+}
+
+func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, createReport,
+		arg.ReporterID,
+		arg.UserID,
+		arg.ChirpID,
+		arg.ChirpBody,
+		arg.Reason,
+		arg.Details,
+	)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.ChirpBody,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.AssigneeID,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getModerationActions = `-- name: GetModerationActions :many
+SELECT id, created_at, moderator_id, action, report_id, user_id, chirp_id, note FROM moderation_actions
+WHERE ($1::uuid IS NULL OR report_id = $1::uuid)
+    AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetModerationActionsParams struct {
+	ReportID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+// newest first, optionally only those about a single report
+func (q *Queries) GetModerationActions(ctx context.Context, arg GetModerationActionsParams) ([]ModerationAction, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationActions,
+		arg.ReportID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationAction
+	for rows.Next() {
+		var i ModerationAction
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ModeratorID,
+			&i.Action,
+			&i.ReportID,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReport = `-- name: GetReport :one
+SELECT id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details, status, assignee_id, resolved_at FROM reports
+WHERE id = $1
+`
+
+func (q *Queries) GetReport(ctx context.Context, id uuid.UUID) (Report, error) {
+	row := q.db.QueryRowContext(ctx, getReport, id)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.ChirpBody,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.AssigneeID,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getReports = `-- name: GetReports :many
+SELECT id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details, status, assignee_id, resolved_at FROM reports
+WHERE ($1::text IS NULL OR status = $1::text)
+    AND ($2::text IS NULL OR reason = $2::text)
+    AND ($3::text IS NULL
+    OR ($3::text = 'chirp' AND chirp_id IS NOT NULL)
+    OR ($3::text = 'user' AND chirp_id IS NULL))
+    AND ($4::uuid IS NULL OR assignee_id = $4::uuid)
+    AND (NOT $5::boolean OR assignee_id IS NULL)
+    AND ($6::timestamp IS NULL
+    OR (created_at, id) > ($6::timestamp, $7::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $8
+`
+
+type GetReportsParams struct {
+	Status          sql.NullString
+	Reason          sql.NullString
+	Target          sql.NullString
+	AssigneeID      uuid.NullUUID
+	Unassigned      bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+// the moderation queue, oldest first. every filter is optional
+func (q *Queries) GetReports(ctx context.Context, arg GetReportsParams) ([]Report, error) {
+	rows, err := q.db.QueryContext(ctx, getReports,
+		arg.Status,
+		arg.Reason,
+		arg.Target,
+		arg.AssigneeID,
+		arg.Unassigned,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Report
+	for rows.Next() {
+		var i Report
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReporterID,
+			&i.UserID,
+			&i.ChirpID,
+			&i.ChirpBody,
+			&i.Reason,
+			&i.Details,
+			&i.Status,
+			&i.AssigneeID,
+			&i.ResolvedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const resolveReport = `-- name: ResolveReport :one
+UPDATE reports
+SET status = $1, resolved_at = NOW(), updated_at = NOW()
+WHERE id = $2 AND status = 'open'
+RETURNING id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details, status, assignee_id, resolved_at
+`
+
+type ResolveReportParams struct {
+	Status string
+	ID     uuid.UUID
+}
+
+// returns no rows if the report was already resolved or dismissed
+func (q *Queries) ResolveReport(ctx context.Context, arg ResolveReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, resolveReport, arg.Status, arg.ID)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.ChirpBody,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.AssigneeID,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, role, suspended_at
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Role,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,130 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: muted_words.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const countMutedWords = `-- name: CountMutedWords :one
+SELECT COUNT(*) FROM muted_words
+WHERE user_id = $1
+    AND phrase <> $2
+    AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+type CountMutedWordsParams struct {
+	UserID uuid.UUID
+	Phrase string
+}
+
+// the user's muted words other than the given phrase, so muting a phrase again works with a full list
+func (q *Queries) CountMutedWords(ctx context.Context, arg CountMutedWordsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMutedWords, arg.UserID, arg.Phrase)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteMutedWord = `-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteMutedWordParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteMutedWord(ctx context.Context, arg DeleteMutedWordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMutedWord, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getMutedWords = `-- name: GetMutedWords :many
+SELECT id, created_at, user_id, phrase, action, expires_at FROM muted_words
+WHERE user_id = $1
+    AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC, id ASC
+`
+
+// the user's muted words that have not expired yet, oldest first
+func (q *Queries) GetMutedWords(ctx context.Context, userID uuid.UUID) ([]MutedWord, error) {
+	rows, err := q.db.QueryContext(ctx, getMutedWords, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MutedWord
+	for rows.Next() {
+		var i MutedWord
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Phrase,
+			&i.Action,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertMutedWord = `-- name: UpsertMutedWord :one
+INSERT INTO muted_words (id, created_at, user_id, phrase, action, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, phrase) DO UPDATE SET action = EXCLUDED.action, expires_at = EXCLUDED.expires_at
+RETURNING id, created_at, user_id, phrase, action, expires_at
+`
+
+type UpsertMutedWordParams struct {
+	UserID    uuid.UUID
+	Phrase    string
+	Action    string
+	ExpiresAt sql.NullTime
+}
+
+// muting a phrase again replaces its action and expiry, also when the earlier one had expired
+func (q *Queries) UpsertMutedWord(ctx context.Context, arg UpsertMutedWordParams) (MutedWord, error) {
+	row := q.db.QueryRowContext(ctx, upsertMutedWord,
+		arg.UserID,
+		arg.Phrase,
+		arg.Action,
+		arg.ExpiresAt,
+	)
+	var i MutedWord
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Phrase,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -0,0 +1,261 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: notifications.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addNotificationActor = `-- name: AddNotificationActor :execrows
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (notification_id, actor_id) DO NOTHING
+`
+
+type AddNotificationActorParams struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+}
+
+func (q *Queries) AddNotificationActor(ctx context.Context, arg AddNotificationActorParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addNotificationActor, arg.NotificationID, arg.ActorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getNotificationActors = `-- name: GetNotificationActors :many
+SELECT ranked.notification_id, ranked.actor_count, users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.role, users.suspended_at FROM (
+    SELECT notification_actors.notification_id, notification_actors.actor_id, notification_actors.created_at,
+        ROW_NUMBER() OVER (PARTITION BY notification_actors.notification_id ORDER BY notification_actors.created_at DESC) AS position,
+        COUNT(*) OVER (PARTITION BY notification_actors.notification_id) AS actor_count
+    FROM notification_actors
+    WHERE notification_actors.notification_id = ANY($1::uuid[])
+) AS ranked
+JOIN users ON users.id = ranked.actor_id
+WHERE ranked.position <= $2::bigint
+ORDER BY ranked.notification_id, ranked.created_at DESC
+`
+
+type GetNotificationActorsParams struct {
+	NotificationIds []uuid.UUID
+	MaxActors       int64
+}
+
+type GetNotificationActorsRow struct {
+	NotificationID uuid.UUID
+	ActorCount     int64
+	User           User
+}
+
+// the most recent actors of each notification, at most max_actors per notification, with the total number of actors
+func (q *Queries) GetNotificationActors(ctx context.Context, arg GetNotificationActorsParams) ([]GetNotificationActorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getNotificationActors, pq.Array(arg.NotificationIds), arg.MaxActors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNotificationActorsRow
+	for rows.Next() {
+		var i GetNotificationActorsRow
+		if err := rows.Scan(
+			&i.NotificationID,
+			&i.ActorCount,
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.IsChirpyRed,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotifications = `-- name: GetNotifications :many
+SELECT id, created_at, updated_at, user_id, type, chirp_id, group_key, read_at FROM notifications
+WHERE notifications.user_id = $1
+    AND (NOT $2::boolean OR notifications.read_at IS NULL)
+    AND NOT EXISTS (SELECT 1 FROM chirps WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NOT NULL)
+    AND ($3::timestamp IS NULL
+    OR (notifications.updated_at, notifications.id) < ($3::timestamp, $4::uuid))
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT $5
+`
+
+type GetNotificationsParams struct {
+	UserID          uuid.UUID
+	UnreadOnly      bool
+	CursorUpdatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+// notifications about chirps that have since been deleted are left out
+func (q *Queries) GetNotifications(ctx context.Context, arg GetNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, getNotifications,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Type,
+			&i.ChirpID,
+			&i.GroupKey,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markAllNotificationsRead, userID)
+	return err
+}
+
+const markNotificationsRead = `-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL AND id = ANY($2::uuid[])
+`
+
+type MarkNotificationsReadParams struct {
+	UserID uuid.UUID
+	Ids    []uuid.UUID
+}
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) error {
+	_, err := q.db.ExecContext(ctx, markNotificationsRead, arg.UserID, pq.Array(arg.Ids))
+	return err
+}
+
+const touchNotification = `-- name: TouchNotification :one
+UPDATE notifications SET updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, type, chirp_id, group_key, read_at
+`
+
+func (q *Queries) TouchNotification(ctx context.Context, id uuid.UUID) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, touchNotification, id)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Type,
+		&i.ChirpID,
+		&i.GroupKey,
+		&i.ReadAt,
+	)
+	return i, err
+}
+
+const upsertNotification = `-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, chirp_id, group_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = notifications.updated_at
+RETURNING id, created_at, updated_at, user_id, type, chirp_id, group_key, read_at
+`
+
+type UpsertNotificationParams struct {
+	UserID   uuid.UUID
+	Type     string
+	ChirpID  uuid.NullUUID
+	GroupKey sql.NullString
+}
+
+// returns the unread notification with the same group key instead of creating a new one, if there is one
+func (q *Queries) UpsertNotification(ctx context.Context, arg UpsertNotificationParams) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, upsertNotification,
+		arg.UserID,
+		arg.Type,
+		arg.ChirpID,
+		arg.GroupKey,
+	)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Type,
+		&i.ChirpID,
+		&i.GroupKey,
+		&i.ReadAt,
+	)
+	return i, err
+}
@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: search.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, chirps.rechirp_of, chirps.quoted_chirp_id, chirps.hidden_at, ts_rank(chirp_search_vectors.search_vector, query)::real AS rank
+FROM chirps
+JOIN chirp_search_vectors ON chirp_search_vectors.chirp_id = chirps.id,
+    websearch_to_tsquery('english', $1) AS query
+WHERE chirp_search_vectors.search_vector @@ query
+    AND chirps.deleted_at IS NULL
+    AND NOT chirp_hidden_from($2::uuid, chirps.user_id, chirps.rechirp_of)
+    AND chirps.hidden_at IS NULL
+    AND ($3::uuid IS NULL OR chirps.user_id = $3::uuid)
+    AND ($4::timestamp IS NULL OR chirps.created_at >= $4::timestamp)
+    AND ($5::timestamp IS NULL OR chirps.created_at < $5::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $7 OFFSET $6
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	ViewerID   uuid.UUID
+	AuthorID   uuid.NullUUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	PageOffset int32
+	PageSize   int32
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+// full-text search using web search syntax: "quoted phrases", OR and -excluded words.
+// results are ranked by relevance, newest first for equal ranks. the vectors are kept up to date by a trigger, see
+// 015_chirps_search.sql.
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.EditedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuotedChirpID,
+			&i.Chirp.HiddenAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,52 @@
+package imageproc
+
+import "errors"
+
+var errMalformedGIF = errors.New("gif: malformed block structure")
+
+// gifFrameCount counts the frames of a GIF by walking its blocks, without decompressing any pixels.
+// gif.DecodeAll decodes every frame into memory, so this is checked first.
+func gifFrameCount(data []byte) (int, error) {
+	// header (6 bytes) and logical screen descriptor (7 bytes)
+	if len(data) < 13 {
+		return 0, errMalformedGIF
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 { // global color table
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i += 2
+		case 0x2C: // image descriptor (10 bytes), local color table, LZW code size, then sub-blocks
+			if i+10 > len(data) {
+				return 0, errMalformedGIF
+			}
+			if flags := data[i+9]; flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i += 11
+			frames++
+		case 0x3B: // trailer
+			return frames, nil
+		default:
+			return 0, errMalformedGIF
+		}
+		// sub-blocks: a length byte and that many bytes, until a length of zero
+		for {
+			if i >= len(data) {
+				return 0, errMalformedGIF
+			}
+			size := int(data[i])
+			i += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	// no trailer. the decoder accepts that too
+	return frames, nil
+}
@@ -0,0 +1,186 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MaxDimension  = 8192       // maximum width or height of an image
+	MaxPixels     = 16_000_000 // maximum width * height, so decoding stays within a sane amount of memory
+	MaxGIFFrames  = 500        // maximum number of frames in a GIF
+	MaxGIFPixels  = 64_000_000 // maximum frames * width * height of a GIF, every frame is decoded at once
+	ThumbnailSize = 320        // thumbnails fit in a square of this many pixels
+	jpegQuality   = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+// Image is an uploaded image after processing
+type Image struct {
+	Data                 []byte
+	ContentType          string
+	Width                int
+	Height               int
+	Thumbnail            []byte
+	ThumbnailContentType string
+}
+
+// Process sniffs the type of an uploaded image, decodes it and encodes it again along with a thumbnail.
+// Re-encoding drops all metadata like EXIF. The EXIF orientation of JPEGs is applied first so photos still
+// show the right way up.
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return Image{}, ErrUnsupportedType
+	}
+
+	// check the size before decoding the pixels, to not fall for decompression bombs
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+	// frames can be no larger than the logical screen, so this bounds the memory DecodeAll needs
+	if contentType == "image/gif" {
+		frames, err := gifFrameCount(data)
+		if err != nil {
+			return Image{}, err
+		}
+		if frames > MaxGIFFrames || frames*config.Width*config.Height > MaxGIFPixels {
+			return Image{}, ErrTooLarge
+		}
+	}
+
+	processed := Image{ContentType: contentType}
+	var img *image.NRGBA
+	var out bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = orient(toNRGBA(decoded), jpegOrientation(data))
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return Image{}, err
+		}
+	case "image/png":
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = toNRGBA(decoded)
+		err = png.Encode(&out, img)
+		if err != nil {
+			return Image{}, err
+		}
+	case "image/gif":
+		// GIFs keep all their frames. only the first one is used for the thumbnail
+		decoded, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		if len(decoded.Image) == 0 {
+			return Image{}, errors.New("gif has no frames")
+		}
+		decoded.Config.Width, decoded.Config.Height = config.Width, config.Height
+		// the first frame can be smaller than the logical screen, draw it where it goes
+		img = image.NewNRGBA(image.Rect(0, 0, config.Width, config.Height))
+		draw.Draw(img, decoded.Image[0].Bounds(), decoded.Image[0], decoded.Image[0].Bounds().Min, draw.Src)
+		err = gif.EncodeAll(&out, decoded)
+		if err != nil {
+			return Image{}, err
+		}
+	}
+	processed.Data = out.Bytes()
+	processed.Width = img.Bounds().Dx()
+	processed.Height = img.Bounds().Dy()
+
+	// thumbnail. PNGs keep their transparency, everything else becomes a JPEG
+	thumb := thumbnail(img, ThumbnailSize)
+	var thumbOut bytes.Buffer
+	if contentType == "image/png" {
+		err = png.Encode(&thumbOut, thumb)
+		processed.ThumbnailContentType = "image/png"
+	} else {
+		err = jpeg.Encode(&thumbOut, thumb, &jpeg.Options{Quality: jpegQuality})
+		processed.ThumbnailContentType = "image/jpeg"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	processed.Thumbnail = thumbOut.Bytes()
+
+	return processed, nil
+}
+
+// Extension returns the file extension for one of the content types Process produces
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// thumbnail scales img down to fit in a size x size square, averaging all source pixels that end up in
+// the same thumbnail pixel. Images that already fit are returned as they are.
+func thumbnail(img *image.NRGBA, size int) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	thumb := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, max((tx+1)*w/tw, tx*w/tw+1)
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := x0; x < x1; x++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[x*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := thumb.PixOffset(tx, ty)
+			for c := 0; c < 4; c++ {
+				thumb.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return thumb
+}
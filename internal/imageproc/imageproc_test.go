@@ -0,0 +1,173 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// halves makes a w x h image, red on the left half and blue on the right
+func halves(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation adds an EXIF APP1 segment with just the orientation tag right after the start of a JPEG
+func withOrientation(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian header, first IFD at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, // orientation, SHORT, count 1
+		0, 0, 0, 0, // no next IFD
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+	app1 := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, segment...)
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestProcessJPEGOrientation(t *testing.T) {
+	data := withOrientation(t, encodeJPEG(t, halves(40, 20)), 6)
+	if o := jpegOrientation(data); o != 6 {
+		t.Fatalf(`jpegOrientation() = %d; expected 6`, o)
+	}
+
+	processed, err := Process(data)
+	if err != nil {
+		t.Fatalf(`Process() error = %v; expected nil`, err)
+	}
+	if processed.Width != 20 || processed.Height != 40 {
+		t.Errorf(`Process() size = %dx%d; expected 20x40`, processed.Width, processed.Height)
+	}
+	if bytes.Contains(processed.Data, []byte("Exif")) {
+		t.Errorf(`Process() kept the EXIF data`)
+	}
+
+	// a 90 degree clockwise turn puts the left half on top
+	img, err := jpeg.Decode(bytes.NewReader(processed.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		x, y    int
+		redder  bool
+		wherein string
+	}{{10, 5, true, "top"}, {10, 35, false, "bottom"}} {
+		r, _, b, _ := img.At(c.x, c.y).RGBA()
+		if (r > b) != c.redder {
+			t.Errorf(`%s of the rotated image has r=%d b=%d`, c.wherein, r>>8, b>>8)
+		}
+	}
+}
+
+func TestProcessThumbnail(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, halves(1000, 500)); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name        string
+		data        []byte
+		contentType string
+	}{
+		{"png", b.Bytes(), "image/png"},
+		{"jpeg", encodeJPEG(t, halves(1000, 500)), "image/jpeg"},
+	}
+
+	for _, c := range cases {
+		processed, err := Process(c.data)
+		if err != nil {
+			t.Fatalf(`Process(%s) error = %v; expected nil`, c.name, err)
+		}
+		if processed.ContentType != c.contentType || processed.ThumbnailContentType != c.contentType {
+			t.Errorf(`Process(%s) content types = %s, %s; expected %s`, c.name, processed.ContentType, processed.ThumbnailContentType, c.contentType)
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(processed.Thumbnail))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != ThumbnailSize || config.Height != ThumbnailSize/2 {
+			t.Errorf(`Process(%s) thumbnail = %dx%d; expected %dx%d`, c.name, config.Width, config.Height, ThumbnailSize, ThumbnailSize/2)
+		}
+	}
+}
+
+// makeGIF encodes a GIF with a width x height logical screen and frames of 1x1 pixel
+func makeGIF(t *testing.T, width, height, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{Config: image.Config{Width: width, Height: height, ColorModel: color.Palette{color.Black, color.White}}}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(i%width, 0, i%width+1, 1), color.Palette{color.Black, color.White}))
+		g.Delay = append(g.Delay, 0)
+	}
+	var b bytes.Buffer
+	if err := gif.EncodeAll(&b, g); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestProcessGIF(t *testing.T) {
+	// the size is that of the logical screen, not of the first frame
+	processed, err := Process(makeGIF(t, 100, 50, 3))
+	if err != nil {
+		t.Fatalf(`Process() error = %v; expected nil`, err)
+	}
+	if processed.Width != 100 || processed.Height != 50 {
+		t.Errorf(`Process() size = %dx%d; expected 100x50`, processed.Width, processed.Height)
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(processed.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 3 {
+		t.Errorf(`Process() kept %d frames; expected 3`, len(decoded.Image))
+	}
+
+	cases := []struct {
+		name                  string
+		width, height, frames int
+	}{
+		{"too many frames", 10, 10, MaxGIFFrames + 1},
+		{"frames times screen too large", 2000, 2000, MaxGIFPixels/(2000*2000) + 1},
+	}
+	for _, c := range cases {
+		data := makeGIF(t, c.width, c.height, c.frames)
+		if n, err := gifFrameCount(data); err != nil || n != c.frames {
+			t.Errorf(`gifFrameCount(%s) = %d, %v; expected %d`, c.name, n, err, c.frames)
+		}
+		if _, err := Process(data); !errors.Is(err, ErrTooLarge) {
+			t.Errorf(`Process(%s) error = %v; expected ErrTooLarge`, c.name, err)
+		}
+	}
+}
+
+func TestProcessUnsupported(t *testing.T) {
+	if _, err := Process([]byte("just some text")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf(`Process() error = %v; expected ErrUnsupportedType`, err)
+	}
+}
@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are the upper bounds in seconds of the default latency histogram buckets
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SizeBuckets are the upper bounds in bytes of the default size histogram buckets
+var SizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// family is a metric with all of its label combinations
+type family interface {
+	writeText(w *bufio.Writer)
+	reset()
+}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format.
+// All of its metrics are safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// WriteText writes every metric in the Prometheus text format, in the order they were made
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+// Reset sets every metric back to zero
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.families {
+		f.reset()
+	}
+}
+
+// Sample is the value of one label combination of a counter
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// CounterVec is a counter with labels. Counters only go up, until the registry is reset.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*Sample // by seriesKey of the label values
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, series: map[string]*Sample{}}
+	r.register(c)
+	return c
+}
+
+// Add adds v to the counter for the given label values, which have to match the labels in number and order
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", c.name, len(c.labels), len(labelValues)))
+	}
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: %s cannot go down", c.name))
+	}
+	key := seriesKey(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &Sample{LabelValues: slices.Clone(labelValues)}
+		c.series[key] = s
+	}
+	s.Value += v
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Samples returns the current value of every label combination, sorted by label values
+func (c *CounterVec) Samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	samples := make([]Sample, 0, len(c.series))
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		samples = append(samples, Sample{LabelValues: slices.Clone(s.LabelValues), Value: s.Value})
+	}
+	return samples
+}
+
+func (c *CounterVec) writeText(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	for _, s := range c.Samples() {
+		writeSample(w, c.name, c.labels, s.LabelValues, "", "", s.Value)
+	}
+}
+
+func (c *CounterVec) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.series = map[string]*Sample{}
+}
+
+// HistogramSample is the state of one label combination of a histogram
+type HistogramSample struct {
+	LabelValues  []string
+	BucketCounts []uint64 // observations per bucket, not cumulative. the last one is +Inf
+	Count        uint64
+	Sum          float64
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*HistogramSample
+}
+
+// NewHistogramVec makes a histogram with the given bucket upper bounds, which have to be sorted
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of %s are not sorted", name))
+	}
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: slices.Clone(buckets), series: map[string]*HistogramSample{}}
+	r.register(h)
+	return h
+}
+
+// Observe records v for the given label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", h.name, len(h.labels), len(labelValues)))
+	}
+	key := seriesKey(labelValues)
+	bucket, _ := slices.BinarySearch(h.buckets, v) // first bucket with an upper bound >= v, or +Inf
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &HistogramSample{LabelValues: slices.Clone(labelValues), BucketCounts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	s.BucketCounts[bucket]++
+	s.Count++
+	s.Sum += v
+}
+
+// Samples returns the current state of every label combination, sorted by label values
+func (h *HistogramVec) Samples() []HistogramSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples := make([]HistogramSample, 0, len(h.series))
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		samples = append(samples, HistogramSample{
+			LabelValues:  slices.Clone(s.LabelValues),
+			BucketCounts: slices.Clone(s.BucketCounts),
+			Count:        s.Count,
+			Sum:          s.Sum,
+		})
+	}
+	return samples
+}
+
+func (h *HistogramVec) writeText(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, s := range h.Samples() {
+		var cumulative uint64
+		for i, count := range s.BucketCounts {
+			cumulative += count
+			le := math.Inf(1)
+			if i < len(h.buckets) {
+				le = h.buckets[i]
+			}
+			writeSample(w, h.name+"_bucket", h.labels, s.LabelValues, "le", formatFloat(le), float64(cumulative))
+		}
+		writeSample(w, h.name+"_sum", h.labels, s.LabelValues, "", "", s.Sum)
+		writeSample(w, h.name+"_count", h.labels, s.LabelValues, "", "", float64(s.Count))
+	}
+}
+
+func (h *HistogramVec) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.series = map[string]*HistogramSample{}
+}
+
+// seriesKey joins label values with a byte that cannot be in valid UTF-8, so different values never collide
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// writeSample writes a single line. extraLabel is for the le label of histogram buckets.
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label, escapeLabelValue(labelValues[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Requests handled.", "route", "code")
+	durations := r.NewHistogramVec("request_duration_seconds", "Request latency.", []float64{0.1, 1}, "route")
+
+	requests.Inc("/api/chirps", "200")
+	requests.Add(2, "/api/chirps", "200")
+	requests.Inc(`/say/"hi"`, "404")
+	durations.Observe(0.05, "/api/chirps")
+	durations.Observe(0.1, "/api/chirps") // upper bounds are inclusive
+	durations.Observe(3, "/api/chirps")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf(`WriteText() = %v; expected nil`, err)
+	}
+	expected := `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{route="/api/chirps",code="200"} 3
+requests_total{route="/say/\"hi\"",code="404"} 1
+# HELP request_duration_seconds Request latency.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{route="/api/chirps",le="0.1"} 2
+request_duration_seconds_bucket{route="/api/chirps",le="1"} 2
+request_duration_seconds_bucket{route="/api/chirps",le="+Inf"} 3
+request_duration_seconds_sum{route="/api/chirps"} 3.15
+request_duration_seconds_count{route="/api/chirps"} 3
+`
+	if b.String() != expected {
+		t.Errorf("WriteText() wrote\n%s\nexpected\n%s", b.String(), expected)
+	}
+
+	// reset empties every metric but keeps them registered
+	r.Reset()
+	if samples := requests.Samples(); len(samples) != 0 {
+		t.Errorf(`Samples() after Reset() = %v; expected none`, samples)
+	}
+	b.Reset()
+	r.WriteText(&b)
+	if !strings.Contains(b.String(), "# TYPE requests_total counter") || strings.Contains(b.String(), "requests_total{") {
+		t.Errorf("WriteText() after Reset() wrote\n%s\nexpected only the headers", b.String())
+	}
+}
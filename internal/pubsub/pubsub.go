@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a single message published on a Hub. IDs only ever go up, so clients can resume after the last ID they saw.
+type Event struct {
+	ID   uint64
+	Type string
+	Data any
+}
+
+// Hub hands published events to every subscriber without ever waiting on one.
+// A subscriber that cannot keep up is dropped: its channel is closed and it can subscribe again,
+// picking up the events it missed from the hub's history.
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event // ring buffer of the most recent events
+	next        int     // where the next event goes in history
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// Subscription receives the events published after it was made
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+}
+
+// NewHub keeps the last historySize events for resuming, and buffers up to bufferSize events per subscriber
+func NewHub(historySize, bufferSize int) *Hub {
+	return &Hub{
+		// IDs start at the current time so they keep going up across restarts,
+		// and IDs from before a restart are simply older than anything in history
+		lastID:      uint64(time.Now().UnixMicro()),
+		history:     make([]Event, 0, historySize),
+		subscribers: map[*Subscription]struct{}{},
+		bufferSize:  bufferSize,
+	}
+}
+
+// Publish sends an event to all subscribers and returns it with its ID
+func (h *Hub) Publish(eventType string, data any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, Data: data}
+	if len(h.history) < cap(h.history) {
+		h.history = append(h.history, event)
+	} else if cap(h.history) > 0 {
+		h.history[h.next] = event
+	}
+	if cap(h.history) > 0 {
+		h.next = (h.next + 1) % cap(h.history)
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default: // too slow, drop it rather than block the publisher
+			h.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe starts a subscription. When afterID is not 0, the events after it that are still in the history
+// are returned as well; together with the subscription they form one gapless stream.
+func (h *Hub) Subscribe(afterID uint64) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := []Event{}
+	if afterID != 0 {
+		for i := range h.history {
+			// oldest first. once the buffer is full, the oldest event is the one that gets overwritten next
+			event := h.history[(h.next+i)%len(h.history)]
+			if event.ID > afterID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &Subscription{hub: h, events: make(chan Event, h.bufferSize)}
+	h.subscribers[sub] = struct{}{}
+	return sub, backlog
+}
+
+// Events is closed when the subscription is closed or dropped for being too slow
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription. Closing twice is fine.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
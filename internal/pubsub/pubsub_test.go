@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, backlog := hub.Subscribe(0)
+	if len(backlog) != 0 {
+		t.Errorf(`Subscribe(0) returned %d events; expected none`, len(backlog))
+	}
+
+	published := hub.Publish("test", "hello")
+	received := <-sub.Events()
+	if received.ID != published.ID || received.Data != "hello" {
+		t.Errorf(`received %v; expected %v`, received, published)
+	}
+
+	// closed subscriptions get nothing and can be closed again
+	sub.Close()
+	sub.Close()
+	hub.Publish("test", "goodbye")
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf(`closed subscription still received an event`)
+	}
+}
+
+func TestResume(t *testing.T) {
+	hub := NewHub(3, 10)
+	events := []Event{}
+	for i := 0; i < 5; i++ {
+		events = append(events, hub.Publish("test", i))
+	}
+
+	// only the last three are kept
+	_, backlog := hub.Subscribe(events[0].ID)
+	if len(backlog) != 3 || backlog[0].ID != events[2].ID || backlog[2].ID != events[4].ID {
+		t.Errorf(`Subscribe(events[0].ID) returned %v; expected the last three events`, backlog)
+	}
+
+	_, backlog = hub.Subscribe(events[3].ID)
+	if len(backlog) != 1 || backlog[0].ID != events[4].ID {
+		t.Errorf(`Subscribe(events[3].ID) returned %v; expected the last event`, backlog)
+	}
+
+	_, backlog = hub.Subscribe(events[4].ID)
+	if len(backlog) != 0 {
+		t.Errorf(`Subscribe(events[4].ID) returned %v; expected no events`, backlog)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	hub := NewHub(10, 2)
+	slow, _ := hub.Subscribe(0)
+
+	// the third event does not fit in the buffer, which drops the subscriber instead of blocking
+	for i := 0; i < 3; i++ {
+		hub.Publish("test", i)
+	}
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 {
+		t.Errorf(`slow subscriber received %d events before being dropped; expected 2`, received)
+	}
+}
@@ -3,18 +3,29 @@ package main
 import (
 	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
 )
 
-func writeError(w http.ResponseWriter, respCode int, err error, msg string) {
-	if err != nil {
-		log.Println(err)
-	}
-	type errorResponse struct {
-		Error string `json:"error"`
+// errorResponse is the body of every error response. Embed it for errors that come with more fields.
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// newErrorResponse logs err along with the request, see logging.go, and returns msg with the request ID,
+// so users can point out the request in the logs. err is never sent.
+func newErrorResponse(w http.ResponseWriter, err error, msg string) errorResponse {
+	requestID := w.Header().Get(requestIDHeader)
+	if err != nil && !recordError(w, err) {
+		slog.Error(msg, "error", err, "request_id", requestID)
 	}
-	e := errorResponse{Error: msg}
-	writeJSON(w, respCode, e)
+	return errorResponse{Error: msg, RequestID: requestID}
+}
+
+// writeError responds with msg and the request ID, see newErrorResponse
+func writeError(w http.ResponseWriter, respCode int, err error, msg string) {
+	writeJSON(w, respCode, newErrorResponse(w, err, msg))
 }
 
 func writeJSON(w http.ResponseWriter, respCode int, payload interface{}) {
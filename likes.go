@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) likeHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+	if _, ok := cfg.requireActiveUser(w, r, userID); !ok { // moderation.go
+		return
+	}
+
+	// check if chirp exists
+	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	if !cfg.requireNotBlocked(w, r, userID, chirp.UserID) { // blocks.go
+		return
+	}
+
+	// like query. liking a chirp twice is a no-op
+	created, err := cfg.db.CreateLike(r.Context(), database.CreateLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when liking chirp")
+		return
+	}
+	if created > 0 {
+		cfg.notify(r.Context(), chirp.UserID, userID, notificationLike, uuid.NullUUID{UUID: chirp.ID, Valid: true}) // notifications.go
+	}
+
+	writeJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unlikeHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// unlike query
+	err = cfg.db.DeleteLike(r.Context(), database.DeleteLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unliking chirp")
+		return
+	}
+
+	writeJSON(w, 204, nil)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// longest X-Request-ID accepted from clients, longer ones are replaced by a fresh ID
+const maxRequestIDLength = 128
+
+// query parameters that commonly carry credentials. chirpy reads none of them, but a misconfigured client might
+// still send a token in the URL, and it should not end up in the logs
+var redactedQueryParams = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"token":         true,
+	"api_key":       true,
+}
+
+type requestIDKey struct{}
+
+// newLogger makes the JSON logger everything logs through, including the log package.
+// level is LOG_LEVEL: debug, info (the default), warn or error.
+func newLogger(out io.Writer, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if level != "" {
+		err := l.UnmarshalText([]byte(level))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: l})), nil
+}
+
+// requestID returns the ID middlewareRequestLog gave the request, or "" outside of a request
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID keeps clients from putting whatever they like in the logs through X-Request-ID
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactQuery returns the query string with the values of redactedQueryParams replaced
+func redactQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	redacted := url.Values{}
+	for key, values := range query {
+		if redactedQueryParams[strings.ToLower(key)] {
+			values = []string{"REDACTED"}
+		}
+		redacted[key] = values
+	}
+	return redacted.Encode()
+}
+
+// authScheme is the part of the Authorization header that is safe to log, like "Bearer" or "ApiKey"
+func authScheme(h http.Header) string {
+	scheme, _, _ := strings.Cut(h.Get("Authorization"), " ")
+	return scheme
+}
+
+// middlewareRequestLog gives every request an ID, taken from X-Request-ID when the client sent a sensible one,
+// and logs the request once it is handled. The ID goes back in the X-Request-ID header and in error responses.
+func (cfg *apiConfig) middlewareRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		r = withRouteHolder(r) // metrics.go
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		// never the token itself
+		attrs := []slog.Attr{
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("route", matchedRoute(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.statusCode()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", rec.size),
+			slog.String("ip", requestIP(r)), // audit.go
+		}
+		if query := redactQuery(r.URL.Query()); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if scheme := authScheme(r.Header); scheme != "" {
+			attrs = append(attrs, slog.String("auth", scheme))
+		}
+		if userID := cfg.optionalUserID(r); userID != uuid.Nil {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+		if rec.err != nil {
+			attrs = append(attrs, slog.String("error", rec.err.Error()))
+		}
+		level := slog.LevelInfo
+		if rec.statusCode() >= 500 {
+			level = slog.LevelError
+		} else if rec.statusCode() >= 400 {
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
@@ -1,73 +1,133 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"sync/atomic"
 	"time"
 
+	"github.com/dcrauwels/chirpy/internal/blobstore"
 	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/internal/pubsub"
+	"github.com/dcrauwels/chirpy/strutils"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	secret         string
-	polkaKey       string
+	metrics            *apiMetrics       // see metrics.go
+	metricsToken       string            // bearer token for GET /metrics, which is not served when empty
+	db                 *database.Queries // queries are timed for metrics
+	dbConn             *sql.DB           // for transactions, queries go through db
+	blobs              blobstore.Store   // uploaded images
+	hub                *pubsub.Hub       // live events, see stream.go
+	secret             string
+	polkaKey           string
+	platform           string                          // dev enables /admin/reset
+	filter             atomic.Pointer[strutils.Filter] // see filter.go
+	filterMode         string                          // mask, reject or flag
+	filterWordsFile    string
+	filterMessages     bool // censor direct messages like chirps
+	chirpyRedMaxLength int
+	// lets the first admin be made through /api/admin/bootstrap, see roles.go
+	adminBootstrapToken string
 }
 
 func main() {
 	// load .env into env variables
 	godotenv.Load()
+
+	// JSON logs, see logging.go. the log package is only used for errors, so it logs at that level
+	logger, err := newLogger(os.Stdout, os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Printf("unknown LOG_LEVEL: %s", err)
+		return
+	}
+	slog.SetDefault(logger)
+	slog.SetLogLoggerLevel(slog.LevelError)
+
 	dbURL := os.Getenv("DB_URL")
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	dbQueries := database.New(db)
+	apiMetrics := newAPIMetrics()
+	dbQueries := database.New(apiMetrics.instrumentDB(db))
+
+	// blob store for uploaded images, served under /media/
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	mediaURL := os.Getenv("MEDIA_URL")
+	if mediaURL == "" {
+		mediaURL = "/media"
+	}
+	blobs, err := blobstore.NewFileStore(mediaDir, mediaURL)
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
 	// apiconfig
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		secret:         os.Getenv("SECRET"),
-		polkaKey:       os.Getenv("POLKA_KEY"),
+		metrics:             apiMetrics,
+		metricsToken:        os.Getenv("METRICS_TOKEN"),
+		db:                  dbQueries,
+		dbConn:              db,
+		blobs:               blobs,
+		hub:                 pubsub.NewHub(256, 64),
+		secret:              os.Getenv("SECRET"),
+		polkaKey:            os.Getenv("POLKA_KEY"),
+		platform:            os.Getenv("PLATFORM"),
+		filterMode:          os.Getenv("FILTER_MODE"),
+		filterWordsFile:     os.Getenv("FILTER_WORDS_FILE"),
+		filterMessages:      os.Getenv("FILTER_MESSAGES") == "true",
+		adminBootstrapToken: os.Getenv("ADMIN_BOOTSTRAP_TOKEN"),
 	}
 
-	// servemux
-	mux := http.NewServeMux()
-
-	// register handlers
-	mux.HandleFunc("GET /api/healthz", readinessHandler)                       //api.go
-	mux.HandleFunc("POST /api/users", apiCfg.postUsersHandler)                 //api.go
-	mux.HandleFunc("PUT /api/users", apiCfg.putUsersHandler)                   //api.go
-	mux.HandleFunc("POST /api/chirps", apiCfg.postChirpsHandler)               //api.go
-	mux.HandleFunc("GET /api/chirps", apiCfg.getChirpsHandler)                 //api.go
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getSingleChirpHandler)  //api.go
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpsHandler) //api.go
-	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)                     //api.go
-	mux.HandleFunc("POST /api/refresh", apiCfg.refreshHandler)                 //api.go
-	mux.HandleFunc("POST /api/revoke", apiCfg.revokeHandler)                   //api.go
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaHandler)            //api.go
-
-	mux.HandleFunc("GET /admin/metrics", apiCfg.hitsHandler) //admin.go
-	mux.HandleFunc("POST /admin/reset", apiCfg.resetHandler) //admin.go
+	// longer chirps for Chirpy Red users
+	apiCfg.chirpyRedMaxLength = defaultChirpyRedMaxLength
+	if l := os.Getenv("CHIRPY_RED_MAX_LENGTH"); l != "" {
+		apiCfg.chirpyRedMaxLength, err = strconv.Atoi(l)
+		if err != nil || apiCfg.chirpyRedMaxLength < maxChirpLength {
+			log.Printf("CHIRPY_RED_MAX_LENGTH has to be a number of at least %d", maxChirpLength)
+			return
+		}
+	}
 
-	// fileserver handler
-	fS := http.FileServer(http.Dir("."))
-	fS = http.StripPrefix("/app/", fS)
+	// word filter. chirps are still censored when the word list cannot be loaded
+	switch apiCfg.filterMode {
+	case "":
+		apiCfg.filterMode = filterModeMask
+	case filterModeMask, filterModeReject, filterModeFlag:
+	default:
+		log.Printf("unknown FILTER_MODE %q", apiCfg.filterMode)
+		return
+	}
+	apiCfg.filter.Store(strutils.NewFilter(defaultFilterWords))
+	err = apiCfg.loadFilter(context.Background())
+	if err != nil {
+		log.Printf("error loading filter words, using the defaults: %s", err)
+	}
 
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(fS)) //middleware in admin.go
+	// request counts survive restarts
+	err = apiCfg.loadRequestCounts(context.Background())
+	if err != nil {
+		log.Printf("error loading request counts, starting from zero: %s", err)
+	}
+	go apiCfg.persistRequestCounts(metricsFlushInterval)
 
 	// server
 	s := http.Server{
 		Addr:                         ":8080",
-		Handler:                      mux,
+		Handler:                      apiCfg.middlewareRequestLog(apiCfg.middlewareMetrics(newMux(&apiCfg, blobs.Handler()))), //logging.go, metrics.go
 		DisableGeneralOptionsHandler: false,
 		ReadTimeout:                  30 * time.Second,
 		WriteTimeout:                 60 * time.Second,
@@ -81,3 +141,112 @@ func main() {
 		}
 	}
 }
+
+// newMux registers every route, with the uploaded images served by media. Kept apart from main so tests can route
+// requests the way the server does.
+func newMux(apiCfg *apiConfig, media http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// register handlers
+	mux.HandleFunc("GET /api/healthz", readinessHandler)                              //api.go
+	mux.HandleFunc("POST /api/users", apiCfg.postUsersHandler)                        //api.go
+	mux.HandleFunc("PUT /api/users", apiCfg.putUsersHandler)                          //api.go
+	mux.HandleFunc("POST /api/chirps", apiCfg.postChirpsHandler)                      //api.go
+	mux.HandleFunc("GET /api/chirps", apiCfg.getChirpsHandler)                        //api.go
+	mux.HandleFunc("GET /api/chirps/stream", apiCfg.streamChirpsHandler)              //stream.go
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.searchChirpsHandler)              //search.go
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getSingleChirpHandler)         //api.go
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpsHandler)        //api.go
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.getThreadHandler)       //threads.go
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", apiCfg.patchChirpsHandler)          //revisions.go
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.getRevisionsHandler) //revisions.go
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.likeHandler)             //likes.go
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.unlikeHandler)         //likes.go
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.rechirpHandler)       //rechirps.go
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.undoRechirpHandler) //rechirps.go
+	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)                            //api.go
+	mux.HandleFunc("POST /api/refresh", apiCfg.refreshHandler)                        //api.go
+	mux.HandleFunc("POST /api/revoke", apiCfg.revokeHandler)                          //api.go
+	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaHandler)                   //api.go
+
+	mux.HandleFunc("GET /api/users/{handleOrID}", apiCfg.getProfileHandler)         //profiles.go
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.followHandler)         //follows.go
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowHandler)     //follows.go
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.getFollowersHandler) //follows.go
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.getFollowingHandler) //follows.go
+	mux.HandleFunc("GET /api/timeline", apiCfg.timelineHandler)                     //follows.go
+	mux.HandleFunc("GET /api/users/me/mentions", apiCfg.getMentionsHandler)         //mentions.go
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.blockHandler)           //blocks.go
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.unblockHandler)       //blocks.go
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.muteHandler)             //blocks.go
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.unmuteHandler)         //blocks.go
+	mux.HandleFunc("GET /api/users/me/blocks", apiCfg.getBlocksHandler)             //blocks.go
+	mux.HandleFunc("GET /api/users/me/mutes", apiCfg.getMutesHandler)               //blocks.go
+
+	mux.HandleFunc("POST /api/users/me/muted-words", apiCfg.postMutedWordsHandler)                  //mutedwords.go
+	mux.HandleFunc("GET /api/users/me/muted-words", apiCfg.getMutedWordsHandler)                    //mutedwords.go
+	mux.HandleFunc("DELETE /api/users/me/muted-words/{mutedWordID}", apiCfg.deleteMutedWordHandler) //mutedwords.go
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotificationsHandler)        //notifications.go
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.readNotificationsHandler) //notifications.go
+	mux.HandleFunc("GET /api/ws", apiCfg.websocketHandler)                          //websocket.go
+
+	mux.HandleFunc("POST /api/conversations", apiCfg.postConversationsHandler)                      //messages.go
+	mux.HandleFunc("GET /api/conversations", apiCfg.getConversationsHandler)                        //messages.go
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.getMessagesHandler)   //messages.go
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.postMessagesHandler) //messages.go
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.readConversationHandler) //messages.go
+
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.trendingHashtagsHandler)     //hashtags.go
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.getHashtagChirpsHandler) //hashtags.go
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.reportChirpHandler) //moderation.go
+	mux.HandleFunc("POST /api/users/{userID}/report", apiCfg.reportUserHandler)    //moderation.go
+
+	mux.HandleFunc("POST /api/admin/bootstrap", apiCfg.bootstrapAdminHandler) //roles.go
+
+	// metrics reveal routes and traffic, so they are only served to scrapers with the token
+	if apiCfg.metricsToken != "" {
+		mux.HandleFunc("GET /metrics", apiCfg.metricsHandler) //metrics.go
+	}
+
+	// everything under /admin/ needs at least a moderator, some of it an admin. see roles.go
+	adminMux := http.NewServeMux()
+	adminOnly := func(h http.HandlerFunc) http.Handler { return apiCfg.middlewareRequireRole(roleAdmin, h) }
+	mux.Handle("/admin/", apiCfg.middlewareRequireRole(roleModerator, middlewareRoutePattern(adminMux)))
+
+	adminMux.Handle("GET /admin/metrics", adminOnly(apiCfg.hitsHandler)) //admin.go
+	adminMux.Handle("POST /admin/reset", adminOnly(apiCfg.resetHandler)) //admin.go
+
+	adminMux.HandleFunc("GET /admin/users", apiCfg.getAdminUsersHandler)                           //adminusers.go
+	adminMux.HandleFunc("GET /admin/users/{userID}", apiCfg.getAdminUserHandler)                   //adminusers.go
+	adminMux.Handle("DELETE /admin/users/{userID}", adminOnly(apiCfg.deleteUserHandler))           //adminusers.go
+	adminMux.Handle("PUT /admin/users/{userID}/role", adminOnly(apiCfg.setUserRoleHandler))        //roles.go
+	adminMux.Handle("POST /admin/users/{userID}/suspend", adminOnly(apiCfg.suspendUserHandler))    //adminusers.go
+	adminMux.Handle("DELETE /admin/users/{userID}/suspend", adminOnly(apiCfg.suspendUserHandler))  //adminusers.go
+	adminMux.Handle("POST /admin/users/{userID}/logout", adminOnly(apiCfg.logoutUserHandler))      //adminusers.go
+	adminMux.Handle("PUT /admin/users/{userID}/chirpy-red", adminOnly(apiCfg.setChirpyRedHandler)) //adminusers.go
+
+	adminMux.Handle("GET /admin/audit", adminOnly(apiCfg.getAuditEventsHandler))           //audit.go
+	adminMux.Handle("GET /admin/audit/export", adminOnly(apiCfg.exportAuditEventsHandler)) //audit.go
+
+	adminMux.HandleFunc("GET /admin/filter/words", apiCfg.getFilterWordsHandler)                    //filter.go
+	adminMux.Handle("POST /admin/filter/words", adminOnly(apiCfg.postFilterWordsHandler))           //filter.go
+	adminMux.Handle("DELETE /admin/filter/words/{word}", adminOnly(apiCfg.deleteFilterWordHandler)) //filter.go
+	adminMux.HandleFunc("GET /admin/filter/flagged", apiCfg.getFlaggedChirpsHandler)                //filter.go
+
+	adminMux.HandleFunc("GET /admin/moderation/reports", apiCfg.getReportsHandler)                        //moderation.go
+	adminMux.HandleFunc("POST /admin/moderation/reports/{reportID}/assign", apiCfg.assignReportHandler)   //moderation.go
+	adminMux.HandleFunc("DELETE /admin/moderation/reports/{reportID}/assign", apiCfg.assignReportHandler) //moderation.go
+	adminMux.HandleFunc("POST /admin/moderation/reports/{reportID}/actions", apiCfg.reportActionHandler)  //moderation.go
+	adminMux.HandleFunc("GET /admin/moderation/actions", apiCfg.getModerationActionsHandler)              //moderation.go
+
+	// fileserver handler
+	fS := http.FileServer(http.Dir("."))
+	fS = http.StripPrefix("/app/", fS)
+
+	mux.Handle("/app/", fS)
+	mux.Handle("/media/", http.StripPrefix("/media/", media))
+
+	return mux
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMuxRoutes(t *testing.T) {
+	mux := newMux(&apiConfig{}, http.NotFoundHandler())
+	cases := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{"GET", "/api/chirps/search", "GET /api/chirps/search"},
+		{"GET", "/api/chirps/stream", "GET /api/chirps/stream"},
+		{"GET", "/api/chirps/8f7e0f4c-6f0a-4d7c-9a53-2a1c0c3f9b10", "GET /api/chirps/{chirpID}"},
+		{"POST", "/api/chirps/8f7e0f4c-6f0a-4d7c-9a53-2a1c0c3f9b10/rechirp", "POST /api/chirps/{chirpID}/rechirp"},
+		{"GET", "/api/users/me/mentions", "GET /api/users/me/mentions"},
+		{"GET", "/api/hashtags/trending", "GET /api/hashtags/trending"},
+		{"GET", "/metrics", ""}, // not served without METRICS_TOKEN
+	}
+
+	for _, c := range cases {
+		_, actual := mux.Handler(httptest.NewRequest(c.method, c.path, nil))
+		if actual != c.expected {
+			t.Errorf(`route(%s %s) = %q; expected %q`, c.method, c.path, actual, c.expected)
+		}
+	}
+}
+
+func TestNewMuxSearch(t *testing.T) {
+	// without q the search handler answers before it needs the database. a request that ends up at
+	// GET /api/chirps/{chirpID} instead gets told "search" is not a valid uuid
+	mux := newMux(&apiConfig{}, http.NotFoundHandler())
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/chirps/search", nil))
+
+	body := struct {
+		Error string `json:"error"`
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 400 || body.Error != "search query 'q' is required" {
+		t.Errorf(`GET /api/chirps/search = %d %q; expected 400 "search query 'q' is required"`, w.Code, body.Error)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/strutils"
+	"github.com/google/uuid"
+)
+
+// Mention is an @handle in a chirp body that belongs to an existing user.
+// Start and End are offsets in characters (Unicode code points) into the body, End being exclusive.
+type Mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+// indexMentions replaces the mentions stored for a chirp with the ones currently in its body.
+// Handles that don't belong to any user are left as plain text.
+func (cfg *apiConfig) indexMentions(ctx context.Context, chirp database.Chirp) error {
+	err := cfg.db.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	mentions := strutils.ExtractMentions(chirp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	// resolve all handles in one query
+	handles := make([]string, len(mentions))
+	for i, mention := range mentions {
+		handles[i] = strings.ToLower(mention.Handle)
+	}
+	users, err := cfg.db.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	userIDs := map[string]uuid.UUID{}
+	for _, user := range users {
+		userIDs[strings.ToLower(user.Handle.String)] = user.ID
+	}
+
+	for _, mention := range mentions {
+		userID, ok := userIDs[strings.ToLower(mention.Handle)]
+		if !ok {
+			continue
+		}
+		err = cfg.db.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, newest first
+	chirps, err := cfg.db.GetMentioningChirps(r.Context(), database.GetMentioningChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting mentions")
+		return
+	}
+
+	// write response
+	chirps, nextCursor := paginate(chirps, page, chirpCursor)
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, userID) // api.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.hideMutedWords(r.Context(), responseChirps, userID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, chirpPage{Chirps: responseChirps, NextCursor: nextCursor})
+}
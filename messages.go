@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMessageLength        = 1000
+	maxConversationSize int = 10 // participants, including whoever starts the conversation
+)
+
+// Message is a direct message in a conversation
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+// ConversationParticipant is a user in a conversation. Everything sent up to LastReadAt has been read by them.
+type ConversationParticipant struct {
+	PublicUser
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+// Conversation is a one-to-one or group conversation as seen by one of its participants
+type Conversation struct {
+	ID           uuid.UUID                 `json:"id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"` // when the last message was sent
+	IsGroup      bool                      `json:"is_group"`
+	Participants []ConversationParticipant `json:"participants"`
+	LastMessage  *Message                  `json:"last_message"`
+	UnreadCount  int64                     `json:"unread_count"`
+}
+
+// conversationPage is a single page of conversations
+type conversationPage struct {
+	Conversations []Conversation `json:"conversations"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// messagePage is a single page of messages
+type messagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+func newMessage(m database.Message) Message {
+	return Message{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+	}
+}
+
+// directKey identifies the one-to-one conversation between two users, whoever started it
+func directKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	slices.Sort(ids)
+	return strings.Join(ids, ":")
+}
+
+// buildConversations looks up participants, last messages and unread counts for all conversations at once
+func (cfg *apiConfig) buildConversations(ctx context.Context, conversations []database.Conversation, viewerID uuid.UUID) ([]Conversation, error) {
+	responseConversations := []Conversation{}
+	if len(conversations) == 0 {
+		return responseConversations, nil
+	}
+	ids := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	// participants
+	participants := map[uuid.UUID][]ConversationParticipant{}
+	participantRows, err := cfg.db.GetConversationParticipants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range participantRows {
+		participant := ConversationParticipant{PublicUser: newPublicUser(row.User)} // api.go
+		if row.LastReadAt.Valid {
+			participant.LastReadAt = &row.LastReadAt.Time
+		}
+		participants[row.ConversationID] = append(participants[row.ConversationID], participant)
+	}
+
+	// last messages
+	lastMessages := map[uuid.UUID]Message{}
+	messageRows, err := cfg.db.GetLastMessages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range messageRows {
+		lastMessages[row.ConversationID] = newMessage(row)
+	}
+
+	// unread counts
+	unreadCounts := map[uuid.UUID]int64{}
+	unreadRows, err := cfg.db.CountUnreadMessages(ctx, database.CountUnreadMessagesParams{
+		UserID:          viewerID,
+		ConversationIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range unreadRows {
+		unreadCounts[row.ConversationID] = row.UnreadCount
+	}
+
+	for _, conversation := range conversations {
+		responseConversation := Conversation{
+			ID:           conversation.ID,
+			CreatedAt:    conversation.CreatedAt,
+			UpdatedAt:    conversation.UpdatedAt,
+			IsGroup:      !conversation.DirectKey.Valid,
+			Participants: participants[conversation.ID],
+			UnreadCount:  unreadCounts[conversation.ID],
+		}
+		if m, ok := lastMessages[conversation.ID]; ok {
+			responseConversation.LastMessage = &m
+		}
+		responseConversations = append(responseConversations, responseConversation)
+	}
+	return responseConversations, nil
+}
+
+// requestConversation reads {conversationID} from the path and checks that the user takes part in it.
+// Writes the error response itself; returns false if it did.
+func (cfg *apiConfig) requestConversation(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (uuid.UUID, bool) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return uuid.Nil, false
+	}
+	isParticipant, err := cfg.db.IsConversationParticipant(r.Context(), database.IsConversationParticipantParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return uuid.Nil, false
+	} else if !isParticipant { // conversations of others are not found rather than forbidden, to not give away they exist
+		writeError(w, 404, errors.New("not a participant"), "conversation not found")
+		return uuid.Nil, false
+	}
+	return conversationID, true
+}
+
+func (cfg *apiConfig) postConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+	if _, ok := cfg.requireActiveUser(w, r, userID); !ok { // moderation.go
+		return
+	}
+
+	// read request
+	reqParams := struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	//the other participants, without duplicates or the user themselves
+	others := []uuid.UUID{}
+	for _, id := range reqParams.ParticipantIDs {
+		if id != userID && !slices.Contains(others, id) {
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		writeError(w, 400, errors.New("no participants"), "a conversation needs at least one other participant")
+		return
+	} else if len(others)+1 > maxConversationSize {
+		writeError(w, 400, errors.New("too many participants"), fmt.Sprintf("a conversation cannot have more than %d participants", maxConversationSize))
+		return
+	}
+
+	// check if the other participants exist
+	users, err := cfg.db.GetUsersByIDs(r.Context(), others)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if len(users) != len(others) {
+		writeError(w, 400, errors.New("unknown participant"), "participant not found")
+		return
+	}
+	blocked, err := cfg.db.IsBlockedByAny(r.Context(), database.IsBlockedByAnyParams{
+		UserID:       userID,
+		OtherUserIds: others,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if blocked {
+		writeError(w, 403, errors.New("blocked"), "user is blocked")
+		return
+	}
+
+	// create conversation and add participants in one go. one-to-one conversations are only created once
+	respCode := 201
+	conversationParams := database.CreateConversationParams{CreatedBy: uuid.NullUUID{UUID: userID, Valid: true}}
+	if len(others) == 1 {
+		conversationParams.DirectKey = sql.NullString{String: directKey(userID, others[0]), Valid: true}
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeError(w, 500, err, "error starting transaction")
+		return
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+	conversation, err := qtx.CreateConversation(r.Context(), conversationParams)
+	if err == sql.ErrNoRows { // the two already have a conversation, so return that one instead
+		respCode = 200
+		conversation, err = qtx.GetConversationByDirectKey(r.Context(), conversationParams.DirectKey)
+	}
+	if err != nil {
+		writeError(w, 500, err, "error querying database when creating conversation")
+		return
+	}
+	for _, participantID := range append(others, userID) {
+		err = qtx.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams{
+			ConversationID: conversation.ID,
+			UserID:         participantID,
+		})
+		if err != nil {
+			writeError(w, 500, err, "error querying database when adding participants")
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		writeError(w, 500, err, "error committing transaction")
+		return
+	}
+
+	// write response
+	responseConversations, err := cfg.buildConversations(r.Context(), []database.Conversation{conversation}, userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, respCode, responseConversations[0])
+}
+
+func (cfg *apiConfig) getConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, most recent message first
+	conversations, err := cfg.db.GetConversationsForUser(r.Context(), database.GetConversationsForUserParams{
+		UserID:          userID,
+		CursorUpdatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting conversations")
+		return
+	}
+
+	// write response
+	conversations, nextCursor := paginate(conversations, page, func(c database.Conversation) cursor {
+		return cursor{CreatedAt: c.UpdatedAt, ID: c.ID}
+	})
+	responseConversations, err := cfg.buildConversations(r.Context(), conversations, userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, conversationPage{Conversations: responseConversations, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	conversationID, ok := cfg.requestConversation(w, r, userID)
+	if !ok {
+		return
+	}
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, newest first
+	messages, err := cfg.db.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID:  conversationID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting messages")
+		return
+	}
+
+	// write response
+	messages, nextCursor := paginate(messages, page, func(m database.Message) cursor {
+		return cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+	})
+	responseMessages := []Message{}
+	for _, message := range messages {
+		responseMessages = append(responseMessages, newMessage(message))
+	}
+	writeJSON(w, 200, messagePage{Messages: responseMessages, NextCursor: nextCursor})
+}
+
+func (cfg *apiConfig) postMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+	if _, ok := cfg.requireActiveUser(w, r, userID); !ok { // moderation.go
+		return
+	}
+
+	// read request
+	conversationID, ok := cfg.requestConversation(w, r, userID)
+	if !ok {
+		return
+	}
+	//group conversations carry on, but a block ends a one-to-one conversation
+	blocked, err := cfg.db.IsBlockedInDirectConversation(r.Context(), database.IsBlockedInDirectConversationParams{
+		UserID:         userID,
+		ConversationID: conversationID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if blocked {
+		writeError(w, 403, errors.New("blocked"), "user is blocked")
+		return
+	}
+	reqParams := struct {
+		Body string `json:"body"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+
+	// checks
+	body := strings.TrimSpace(reqParams.Body)
+	if body == "" {
+		writeError(w, 400, errors.New("empty message"), "message cannot be empty")
+		return
+	} else if utf8.RuneCountInString(body) > maxMessageLength {
+		writeError(w, 400, errors.New("message too long"), fmt.Sprintf("message cannot exceed %d characters", maxMessageLength))
+		return
+	}
+	//messages are private, so they are only filtered like chirps when FILTER_MESSAGES is set.
+	//there is nothing to flag them for, so in flag mode they are masked instead
+	if cfg.filterMessages && cfg.filterMode == filterModeFlag {
+		body, _ = cfg.filter.Load().Mask(body)
+	} else if cfg.filterMessages {
+		body, _, err = cfg.filterText(body) // filter.go
+		if err == errFilteredWords {
+			writeError(w, 400, err, "message contains words that are not allowed")
+			return
+		}
+	}
+
+	// create message
+	message, err := cfg.db.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           body,
+	})
+	if err != nil {
+		writeError(w, 500, err, "server error creating message")
+		return
+	}
+
+	writeJSON(w, 201, newMessage(message))
+}
+
+func (cfg *apiConfig) readConversationHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	conversationID, ok := cfg.requestConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// update query. this is the read receipt the other participants see as last_read_at
+	err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when marking conversation read")
+		return
+	}
+
+	writeJSON(w, 204, nil)
+}
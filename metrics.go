@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/auth"
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/internal/metrics"
+)
+
+// how often request counts are written to the database
+const metricsFlushInterval = 30 * time.Second
+
+// unmatchedRoute is the route label of requests that matched no route, so random paths cannot blow up the number of series
+const unmatchedRoute = "unmatched"
+
+// apiMetrics are the metrics served at GET /metrics
+type apiMetrics struct {
+	registry        *metrics.Registry
+	requests        *metrics.CounterVec   // route, method, code
+	requestDuration *metrics.HistogramVec // route, method
+	responseSize    *metrics.HistogramVec // route
+	dbQueryDuration *metrics.HistogramVec // query
+	dbQueryErrors   *metrics.CounterVec   // query
+
+	mu        sync.Mutex         // for persisting and resetting request counts
+	persisted map[string]float64 // request counts as far as they are in the database, by label values
+}
+
+func newAPIMetrics() *apiMetrics {
+	r := metrics.NewRegistry()
+	return &apiMetrics{
+		registry:        r,
+		requests:        r.NewCounterVec("chirpy_http_requests_total", "HTTP requests handled, by route pattern, method and status code. Kept across restarts.", "route", "method", "code"),
+		requestDuration: r.NewHistogramVec("chirpy_http_request_duration_seconds", "Time taken to handle HTTP requests. Streams and websockets count until they close.", metrics.DurationBuckets, "route", "method"),
+		responseSize:    r.NewHistogramVec("chirpy_http_response_size_bytes", "Size of HTTP response bodies.", metrics.SizeBuckets, "route"),
+		dbQueryDuration: r.NewHistogramVec("chirpy_db_query_duration_seconds", "Time taken by database queries, by sqlc query name.", metrics.DurationBuckets, "query"),
+		dbQueryErrors:   r.NewCounterVec("chirpy_db_query_errors_total", "Database queries that failed, by sqlc query name.", "query"),
+		persisted:       map[string]float64{},
+	}
+}
+
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// loadRequestCounts adds the counts persisted before the last restart to the in-memory counters
+func (cfg *apiConfig) loadRequestCounts(ctx context.Context) error {
+	m := cfg.metrics
+	rows, err := cfg.db.GetRequestCounts(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range rows {
+		labelValues := []string{row.Route, row.Method, strconv.Itoa(int(row.StatusCode))}
+		m.requests.Add(float64(row.Count), labelValues...)
+		m.persisted[seriesKey(labelValues)] += float64(row.Count)
+	}
+	return nil
+}
+
+// flushRequestCounts writes what the request counters went up since the last flush to the database
+func (cfg *apiConfig) flushRequestCounts(ctx context.Context) error {
+	m := cfg.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.requests.Samples() {
+		key := seriesKey(s.LabelValues)
+		delta := s.Value - m.persisted[key]
+		if delta <= 0 {
+			continue
+		}
+		code, _ := strconv.Atoi(s.LabelValues[2])
+		err := cfg.db.AddRequestCount(ctx, database.AddRequestCountParams{
+			Route:      s.LabelValues[0],
+			Method:     s.LabelValues[1],
+			StatusCode: int32(code),
+			Count:      int64(delta),
+		})
+		if err != nil {
+			return err
+		}
+		m.persisted[key] = s.Value
+	}
+	return nil
+}
+
+// persistRequestCounts flushes the request counts every interval. It never returns.
+func (cfg *apiConfig) persistRequestCounts(interval time.Duration) {
+	for range time.Tick(interval) {
+		err := cfg.flushRequestCounts(context.Background())
+		if err != nil {
+			log.Printf("error persisting request counts: %s", err)
+		}
+	}
+}
+
+// resetMetrics sets every metric back to zero, both in memory and in the database
+func (cfg *apiConfig) resetMetrics(ctx context.Context) error {
+	m := cfg.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := cfg.db.ResetRequestCounts(ctx)
+	if err != nil {
+		return err
+	}
+	m.registry.Reset()
+	m.persisted = map[string]float64{}
+	return nil
+}
+
+// instrumentedDB times every query that goes through it. sqlc starts each query with its name, which is used as label.
+type instrumentedDB struct {
+	db      database.DBTX
+	metrics *apiMetrics
+}
+
+func (m *apiMetrics) instrumentDB(db database.DBTX) database.DBTX {
+	return instrumentedDB{db: db, metrics: m}
+}
+
+// queriesWithTx is cfg.db.WithTx, but with the queries in the transaction timed as well
+func (cfg *apiConfig) queriesWithTx(tx *sql.Tx) *database.Queries {
+	return database.New(cfg.metrics.instrumentDB(tx))
+}
+
+func queryName(query string) string {
+	name, found := strings.CutPrefix(query, "-- name: ")
+	if !found {
+		return "unknown"
+	}
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
+
+func (d instrumentedDB) observe(query string, start time.Time, err error) {
+	name := queryName(query)
+	d.metrics.dbQueryDuration.Observe(time.Since(start).Seconds(), name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		d.metrics.dbQueryErrors.Inc(name)
+	}
+}
+
+func (d instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := d.db.ExecContext(ctx, query, args...)
+	d.observe(query, start, err)
+	return result, err
+}
+
+func (d instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, query)
+}
+
+func (d instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	d.observe(query, start, err)
+	return rows, err
+}
+
+func (d instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.db.QueryRowContext(ctx, query, args...)
+	d.observe(query, start, row.Err())
+	return row
+}
+
+// responseRecorder remembers the status code and body size of a response, and the error writeError was given.
+// It passes on flushing for server-sent events and hijacking for websockets.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	err    error
+}
+
+func (mw *responseRecorder) WriteHeader(code int) {
+	if mw.status == 0 {
+		mw.status = code
+	}
+	mw.ResponseWriter.WriteHeader(code)
+}
+
+func (mw *responseRecorder) Write(b []byte) (int, error) {
+	if mw.status == 0 {
+		mw.status = http.StatusOK
+	}
+	n, err := mw.ResponseWriter.Write(b)
+	mw.size += int64(n)
+	return n, err
+}
+
+func (mw *responseRecorder) Flush() {
+	if mw.status == 0 {
+		mw.status = http.StatusOK
+	}
+	if f, ok := mw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (mw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := mw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	mw.status = http.StatusSwitchingProtocols
+	return hj.Hijack()
+}
+
+// Unwrap is for http.ResponseController
+func (mw *responseRecorder) Unwrap() http.ResponseWriter {
+	return mw.ResponseWriter
+}
+
+// statusCode is the status the response went out with. Handlers that write nothing at all send a 200.
+func (mw *responseRecorder) statusCode() int {
+	if mw.status == 0 {
+		return http.StatusOK
+	}
+	return mw.status
+}
+
+// recordError hands err to every responseRecorder wrapped around w, so the request log can include it.
+// Returns false when there is none.
+func recordError(w http.ResponseWriter, err error) bool {
+	recorded := false
+	for {
+		if mw, ok := w.(*responseRecorder); ok {
+			mw.err = err
+			recorded = true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return recorded
+		}
+		w = u.Unwrap()
+	}
+}
+
+type routeKey struct{}
+
+// withRouteHolder gives the request a place for nested muxes to report their pattern in, unless it has one already
+func withRouteHolder(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(routeKey{}).(*string); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, new(string)))
+}
+
+// middlewareMetrics records every request under the pattern of the route it matched
+func (cfg *apiConfig) middlewareMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r = withRouteHolder(r)
+		mw := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(mw, r)
+
+		routeLabel, methodLabel := matchedRoute(r), r.Method
+		if routeLabel == unmatchedRoute {
+			methodLabel = ""
+		}
+		cfg.metrics.requests.Inc(routeLabel, methodLabel, strconv.Itoa(mw.statusCode()))
+		cfg.metrics.requestDuration.Observe(time.Since(start).Seconds(), routeLabel, methodLabel)
+		cfg.metrics.responseSize.Observe(float64(mw.size), routeLabel)
+	})
+}
+
+// middlewareRoutePattern passes the pattern a nested mux, or a mux behind middleware that copies the request,
+// matched on to middlewareMetrics. The innermost mux knows the full pattern, so the first one to report wins.
+func middlewareRoutePattern(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok && *route == "" {
+			*route = r.Pattern
+		}
+	})
+}
+
+// matchedRoute is the path of the pattern the request matched, once it has been handled. The mux sets the pattern
+// on the request it was handed, nested muxes report theirs through routeKey.
+func matchedRoute(r *http.Request) string {
+	pattern := r.Pattern
+	if route, ok := r.Context().Value(routeKey{}).(*string); ok && *route != "" {
+		pattern = *route
+	}
+	if _, path, found := strings.Cut(pattern, " "); found {
+		pattern = path
+	}
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	return pattern
+}
+
+// metricsHandler serves the metrics in the Prometheus text format to scrapers that send METRICS_TOKEN as bearer token.
+// Without METRICS_TOKEN the route is not registered at all.
+func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if cfg.metricsToken == "" || err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.metricsToken)) != 1 {
+		writeError(w, 401, err, "metrics token missing or incorrect")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	err = cfg.metrics.registry.WriteText(w)
+	if err != nil {
+		log.Printf("error writing metrics: %s", err)
+	}
+}
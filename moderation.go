@@ -0,0 +1,541 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// report statuses
+const (
+	reportOpen      = "open"
+	reportResolved  = "resolved"
+	reportDismissed = "dismissed"
+)
+
+// moderator actions on a report
+const (
+	actionHideChirp   = "hide_chirp"   // leaves the chirp out of every listing, it only shows as a placeholder in threads
+	actionRemoveChirp = "remove_chirp" // deletes the chirp like its author would
+	actionSuspendUser = "suspend_user" // logs the user out and keeps them from logging in, posting and showing up anywhere
+	actionDismiss     = "dismiss"      // closes the report without doing anything
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+
+const maxReportDetailsLength = 1000
+
+// Report is a user's complaint about a chirp or another user
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	UserID     uuid.UUID  `json:"user_id"`    // the reported user, or the author of the reported chirp
+	ChirpID    *uuid.UUID `json:"chirp_id"`   // null for reports about a user
+	ChirpBody  *string    `json:"chirp_body"` // the body as it was when reported
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	AssigneeID *uuid.UUID `json:"assignee_id"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+func newReport(r database.Report) Report {
+	report := Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		ReporterID: r.ReporterID,
+		UserID:     r.UserID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+	}
+	if r.ChirpID.Valid {
+		report.ChirpID = &r.ChirpID.UUID
+	}
+	if r.ChirpBody.Valid {
+		report.ChirpBody = &r.ChirpBody.String
+	}
+	if r.AssigneeID.Valid {
+		report.AssigneeID = &r.AssigneeID.UUID
+	}
+	if r.ResolvedAt.Valid {
+		report.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return report
+}
+
+// ModerationAction is the record of something a moderator did
+type ModerationAction struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	Action      string     `json:"action"`
+	ReportID    *uuid.UUID `json:"report_id"`
+	UserID      *uuid.UUID `json:"user_id"`
+	ChirpID     *uuid.UUID `json:"chirp_id"`
+	Note        string     `json:"note"`
+}
+
+func newModerationAction(a database.ModerationAction) ModerationAction {
+	action := ModerationAction{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		Action:    a.Action,
+		Note:      a.Note,
+	}
+	for _, id := range []struct {
+		from uuid.NullUUID
+		to   **uuid.UUID
+	}{{a.ModeratorID, &action.ModeratorID}, {a.ReportID, &action.ReportID}, {a.UserID, &action.UserID}, {a.ChirpID, &action.ChirpID}} {
+		if id.from.Valid {
+			*id.to = &id.from.UUID
+		}
+	}
+	return action
+}
+
+// requireActiveUser writes a 403 and returns false if the user was suspended, for anything that creates content or
+// reaches out to other users. access tokens stay valid until they expire, so logging out alone is not enough
+func (cfg *apiConfig) requireActiveUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.User, bool) {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return database.User{}, false
+	}
+	if user.SuspendedAt.Valid {
+		writeError(w, 403, errors.New("user suspended"), "account is suspended")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// createReport reads the reason and details from the request and stores the report
+func (cfg *apiConfig) createReport(w http.ResponseWriter, r *http.Request, reportParams database.CreateReportParams) {
+	reqParams := struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	if !slices.Contains(reportReasons, reqParams.Reason) {
+		writeError(w, 400, errors.New("unknown reason"), "reason should be one of "+strings.Join(reportReasons, ", "))
+		return
+	}
+	reportParams.Reason = reqParams.Reason
+	reportParams.Details = strings.TrimSpace(reqParams.Details)
+	if utf8.RuneCountInString(reportParams.Details) > maxReportDetailsLength {
+		writeError(w, 400, errors.New("details too long"), fmt.Sprintf("details cannot exceed %d characters", maxReportDetailsLength))
+		return
+	}
+
+	// create report. one open report per reporter and chirp or user
+	report, err := cfg.db.CreateReport(r.Context(), reportParams)
+	if isUniqueViolation(err, "reports_open_chirp_idx") || isUniqueViolation(err, "reports_open_user_idx") { // dberrors.go
+		writeError(w, 409, err, "already reported")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when creating report")
+		return
+	}
+
+	writeJSON(w, 201, newReport(report))
+}
+
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// check if chirp exists
+	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if chirp.UserID == userID {
+		writeError(w, 400, errors.New("self report"), "users cannot report their own chirps")
+		return
+	}
+
+	cfg.createReport(w, r, database.CreateReportParams{
+		ReporterID: userID,
+		UserID:     chirp.UserID,
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ChirpBody:  sql.NullString{String: chirp.Body, Valid: true},
+	})
+}
+
+func (cfg *apiConfig) reportUserHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	reportedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	} else if reportedID == userID {
+		writeError(w, 400, errors.New("self report"), "users cannot report themselves")
+		return
+	}
+	_, err = cfg.db.GetUserByID(r.Context(), reportedID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+
+	cfg.createReport(w, r, database.CreateReportParams{
+		ReporterID: userID,
+		UserID:     reportedID,
+	})
+}
+
+func (cfg *apiConfig) getReportsHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID := staffUser(r).ID // roles.go
+
+	// read request
+	//query parameters
+	query := r.URL.Query()
+	params := database.GetReportsParams{}
+	switch status := query.Get("status"); status {
+	case "":
+		params.Status = sql.NullString{String: reportOpen, Valid: true}
+	case reportOpen, reportResolved, reportDismissed:
+		params.Status = sql.NullString{String: status, Valid: true}
+	case "all":
+	default:
+		writeError(w, 400, errors.New("incorrect query parameter"), "status should be 'open', 'resolved', 'dismissed' or 'all'")
+		return
+	}
+	if reason := query.Get("reason"); reason != "" {
+		if !slices.Contains(reportReasons, reason) {
+			writeError(w, 400, errors.New("incorrect query parameter"), "reason should be one of "+strings.Join(reportReasons, ", "))
+			return
+		}
+		params.Reason = sql.NullString{String: reason, Valid: true}
+	}
+	switch target := query.Get("type"); target {
+	case "":
+	case "chirp", "user":
+		params.Target = sql.NullString{String: target, Valid: true}
+	default:
+		writeError(w, 400, errors.New("incorrect query parameter"), "type should be either 'chirp' or 'user'")
+		return
+	}
+	switch assignee := query.Get("assignee"); assignee {
+	case "":
+	case "me":
+		params.AssigneeID = uuid.NullUUID{UUID: moderatorID, Valid: true}
+	case "none":
+		params.Unassigned = true
+	default:
+		assigneeID, err := uuid.Parse(assignee)
+		if err != nil {
+			writeError(w, 400, err, "assignee should be 'me', 'none' or a user ID")
+			return
+		}
+		params.AssigneeID = uuid.NullUUID{UUID: assigneeID, Valid: true}
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	params.CursorCreatedAt = page.cursorCreatedAt()
+	params.CursorID = page.cursorID()
+	params.PageSize = page.fetchSize()
+
+	// query DB, oldest first so nothing waits forever
+	reports, err := cfg.db.GetReports(r.Context(), params)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting reports")
+		return
+	}
+
+	// write response
+	reports, nextCursor := paginate(reports, page, func(report database.Report) cursor {
+		return cursor{CreatedAt: report.CreatedAt, ID: report.ID}
+	})
+	responseReports := []Report{}
+	for _, report := range reports {
+		responseReports = append(responseReports, newReport(report))
+	}
+	writeJSON(w, 200, struct {
+		Reports    []Report `json:"reports"`
+		NextCursor string   `json:"next_cursor,omitempty"`
+	}{responseReports, nextCursor})
+}
+
+func (cfg *apiConfig) assignReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID := staffUser(r).ID // roles.go
+
+	// read request
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	//POST assigns the report to the given moderator, or to the one making the request. DELETE unassigns it
+	assigneeID := uuid.NullUUID{}
+	if r.Method == http.MethodPost {
+		reqParams := struct {
+			AssigneeID *uuid.UUID `json:"assignee_id"`
+		}{}
+		if r.ContentLength != 0 {
+			decoder := json.NewDecoder(r.Body)
+			err = decoder.Decode(&reqParams)
+			if err != nil {
+				writeError(w, 400, err, "request has incorrect JSON structure")
+				return
+			}
+		}
+		assigneeID = uuid.NullUUID{UUID: moderatorID, Valid: true}
+		if reqParams.AssigneeID != nil && *reqParams.AssigneeID != moderatorID {
+			assignee, err := cfg.db.GetUserByID(r.Context(), *reqParams.AssigneeID)
+			if err == sql.ErrNoRows || (err == nil && !hasRole(assignee.Role, roleModerator)) {
+				writeError(w, 400, errors.New("not a moderator"), "reports can only be assigned to moderators")
+				return
+			} else if err != nil {
+				writeError(w, 500, err, "error querying database")
+				return
+			}
+			assigneeID.UUID = assignee.ID
+		}
+	}
+
+	// update query
+	report, err := cfg.db.AssignReport(r.Context(), database.AssignReportParams{
+		ID:         reportID,
+		AssigneeID: assigneeID,
+	})
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "report not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when assigning report")
+		return
+	}
+
+	writeJSON(w, 200, newReport(report))
+}
+
+func (cfg *apiConfig) reportActionHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID := staffUser(r).ID // roles.go
+
+	// read request
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	reqParams := struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	report, err := cfg.db.GetReport(r.Context(), reportID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "report not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	switch reqParams.Action {
+	case actionHideChirp, actionRemoveChirp:
+		if !report.ChirpID.Valid {
+			writeError(w, 400, errors.New("not a chirp report"), "report is not about a chirp")
+			return
+		}
+	case actionSuspendUser:
+		// staff can only be suspended by an admin, through POST /admin/users/{userID}/suspend
+		reported, err := cfg.db.GetUserByID(r.Context(), report.UserID)
+		if err == sql.ErrNoRows {
+			writeError(w, 404, err, "reported user not found")
+			return
+		} else if err != nil {
+			writeError(w, 500, err, "error querying database")
+			return
+		}
+		if hasRole(reported.Role, roleModerator) {
+			writeError(w, 403, errors.New("reported user is staff"), "moderators and admins cannot be suspended through reports")
+			return
+		}
+	case actionDismiss:
+	default:
+		writeError(w, 400, errors.New("unknown action"), "action should be 'hide_chirp', 'remove_chirp', 'suspend_user' or 'dismiss'")
+		return
+	}
+
+	// close the report, act on it and record what was done in one go
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeError(w, 500, err, "error starting transaction")
+		return
+	}
+	defer tx.Rollback()          // no-op after commit
+	qtx := cfg.queriesWithTx(tx) // metrics.go
+	status := reportResolved
+	if reqParams.Action == actionDismiss {
+		status = reportDismissed
+	}
+	_, err = qtx.ResolveReport(r.Context(), database.ResolveReportParams{ID: reportID, Status: status})
+	if err == sql.ErrNoRows {
+		writeError(w, 409, err, "report is already closed")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when closing report")
+		return
+	}
+	actionParams := database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Action:      reqParams.Action,
+		ReportID:    uuid.NullUUID{UUID: reportID, Valid: true},
+		UserID:      uuid.NullUUID{UUID: report.UserID, Valid: true},
+		ChirpID:     report.ChirpID,
+		Note:        strings.TrimSpace(reqParams.Note),
+	}
+	var removed *database.Chirp // for the event and attachments, which only go after the commit
+	switch reqParams.Action {
+	case actionHideChirp:
+		err = qtx.HideChirp(r.Context(), report.ChirpID.UUID)
+	case actionRemoveChirp:
+		chirp, err := qtx.DeleteSingleChirp(r.Context(), database.DeleteSingleChirpParams{ID: report.ChirpID.UUID, UserID: report.UserID})
+		if err == nil {
+			removed = &chirp
+		} else if err != sql.ErrNoRows { // no rows means the author deleted it already
+			writeError(w, 500, err, "error querying database when removing chirp")
+			return
+		}
+		err = nil
+	case actionSuspendUser:
+		_, err = qtx.SuspendUser(r.Context(), report.UserID)
+		if err == nil {
+			_, err = qtx.RevokeRefreshTokensForUser(r.Context(), report.UserID)
+		}
+	}
+	if err != nil {
+		writeError(w, 500, err, "error querying database when acting on report")
+		return
+	}
+	action, err := qtx.CreateModerationAction(r.Context(), actionParams)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when recording action")
+		return
+	}
+	auditEvent, err := auditParams(r, moderatorID, auditModerationAction, report.UserID, map[string]any{
+		"action":    action.Action,
+		"report_id": reportID,
+		"chirp_id":  report.ChirpID,
+	}) // audit.go
+	if err == nil {
+		_, err = qtx.CreateAuditEvent(r.Context(), auditEvent)
+	}
+	if err != nil {
+		writeError(w, 500, err, "error recording audit event")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		writeError(w, 500, err, "error committing transaction")
+		return
+	}
+
+	// hidden and removed chirps disappear from live streams as well
+	if removed != nil {
+		err = cfg.deleteAttachments(r.Context(), removed.ID) // attachments.go
+		if err != nil {
+			writeError(w, 500, err, "error deleting attachments")
+			return
+		}
+		cfg.publishChirpDeleted(*removed) // stream.go
+	} else if reqParams.Action == actionHideChirp {
+		chirp, err := cfg.db.GetSingleChirp(r.Context(), report.ChirpID.UUID)
+		if err == nil {
+			cfg.publishChirpDeleted(chirp)
+		}
+	}
+
+	writeJSON(w, 201, newModerationAction(action))
+}
+
+func (cfg *apiConfig) getModerationActionsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	params := database.GetModerationActionsParams{}
+	if reportID := r.URL.Query().Get("report_id"); reportID != "" {
+		id, err := uuid.Parse(reportID)
+		if err != nil {
+			writeError(w, 400, err, "invalid report ID provided")
+			return
+		}
+		params.ReportID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	page, err := readPageParams(r)
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	params.CursorCreatedAt = page.cursorCreatedAt()
+	params.CursorID = page.cursorID()
+	params.PageSize = page.fetchSize()
+
+	// query DB, newest first
+	actions, err := cfg.db.GetModerationActions(r.Context(), params)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting moderation actions")
+		return
+	}
+
+	// write response
+	actions, nextCursor := paginate(actions, page, func(a database.ModerationAction) cursor {
+		return cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+	})
+	responseActions := []ModerationAction{}
+	for _, action := range actions {
+		responseActions = append(responseActions, newModerationAction(action))
+	}
+	writeJSON(w, 200, struct {
+		Actions    []ModerationAction `json:"actions"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}{responseActions, nextCursor})
+}
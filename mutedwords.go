@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/strutils"
+	"github.com/google/uuid"
+)
+
+// muted word actions
+const (
+	mutedWordHide     = "hide"
+	mutedWordCollapse = "collapse"
+)
+
+const (
+	maxMutedWords        = 200
+	maxMutedPhraseLength = 100
+)
+
+// MutedWord is a word, phrase or #hashtag a user does not want to see chirps about
+type MutedWord struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Phrase    string     `json:"phrase"`
+	Action    string     `json:"action"`
+	ExpiresAt *time.Time `json:"expires_at"` // null for muted words that never expire
+}
+
+func newMutedWord(m database.MutedWord) MutedWord {
+	mutedWord := MutedWord{
+		ID:        m.ID,
+		CreatedAt: m.CreatedAt,
+		Phrase:    m.Phrase,
+		Action:    m.Action,
+	}
+	if m.ExpiresAt.Valid {
+		mutedWord.ExpiresAt = &m.ExpiresAt.Time
+	}
+	return mutedWord
+}
+
+// mutedWords is a viewer's list of muted words, applied to chirps when they are read so the stored body is never touched
+type mutedWords []database.MutedWord
+
+// loadMutedWords returns the viewer's muted words, or none for anonymous viewers
+func (cfg *apiConfig) loadMutedWords(ctx context.Context, viewerID uuid.UUID) (mutedWords, error) {
+	if viewerID == uuid.Nil {
+		return nil, nil
+	}
+	return cfg.db.GetMutedWords(ctx, viewerID)
+}
+
+// match returns the muted phrases a chirp contains, and whether any of them hides the chirp.
+// A rechirp matches on the chirp it rechirps, and a quote chirp on the chirp it quotes as well.
+// Expiry is checked here as well, for lists kept around like on websockets.
+func (m mutedWords) match(chirp Chirp) (phrases []string, hide bool) {
+	if chirp.RechirpOf != nil {
+		chirp = *chirp.RechirpOf
+	}
+	bodies := []string{chirp.Body}
+	if chirp.QuotedChirp != nil {
+		bodies = append(bodies, chirp.QuotedChirp.Body)
+	}
+	now := time.Now()
+	for _, mutedWord := range m {
+		if mutedWord.ExpiresAt.Valid && !mutedWord.ExpiresAt.Time.After(now) {
+			continue
+		}
+		if slices.ContainsFunc(bodies, func(body string) bool { return strutils.ContainsPhrase(body, mutedWord.Phrase) }) {
+			phrases = append(phrases, mutedWord.Phrase)
+			hide = hide || mutedWord.Action == mutedWordHide
+		}
+	}
+	return phrases, hide
+}
+
+// apply marks every chirp that matches a muted word, and leaves out the ones that should be hidden if hide is set.
+// Where leaving chirps out makes no sense, like in threads, everything matching is only marked.
+func (m mutedWords) apply(chirps []Chirp, hide bool) []Chirp {
+	if len(m) == 0 {
+		return chirps
+	}
+	filtered := []Chirp{}
+	for _, chirp := range chirps {
+		phrases, hidden := m.match(chirp)
+		if hide && hidden {
+			continue
+		}
+		chirp.MutedWords = phrases
+		filtered = append(filtered, chirp)
+	}
+	return filtered
+}
+
+// hideMutedWords applies the viewer's muted words to a feed
+func (cfg *apiConfig) hideMutedWords(ctx context.Context, chirps []Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	m, err := cfg.loadMutedWords(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return m.apply(chirps, true), nil
+}
+
+// collapseMutedWords applies the viewer's muted words to chirps they asked for specifically, like a thread
+func (cfg *apiConfig) collapseMutedWords(ctx context.Context, chirps []Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	m, err := cfg.loadMutedWords(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return m.apply(chirps, false), nil
+}
+
+func (cfg *apiConfig) postMutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	reqParams := struct {
+		Phrase    string     `json:"phrase"`
+		Action    string     `json:"action"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+
+	// checks
+	phrase := strutils.NormalizePhrase(reqParams.Phrase)
+	if phrase == "" {
+		writeError(w, 400, errors.New("empty phrase"), "phrase cannot be empty")
+		return
+	} else if utf8.RuneCountInString(phrase) > maxMutedPhraseLength {
+		writeError(w, 400, errors.New("phrase too long"), fmt.Sprintf("phrase cannot exceed %d characters", maxMutedPhraseLength))
+		return
+	}
+	for _, c := range phrase {
+		if unicode.IsControl(c) {
+			writeError(w, 400, errors.New("control character"), "phrase cannot contain control characters")
+			return
+		}
+	}
+	action := reqParams.Action
+	if action == "" {
+		action = mutedWordHide
+	} else if action != mutedWordHide && action != mutedWordCollapse {
+		writeError(w, 400, errors.New("incorrect action"), "action should be either 'hide' or 'collapse'")
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if reqParams.ExpiresAt != nil {
+		if !reqParams.ExpiresAt.After(time.Now()) {
+			writeError(w, 400, errors.New("expiry in the past"), "expires_at has to be in the future")
+			return
+		}
+		expiresAt.Time, expiresAt.Valid = reqParams.ExpiresAt.UTC(), true
+	}
+	count, err := cfg.db.CountMutedWords(r.Context(), database.CountMutedWordsParams{UserID: userID, Phrase: phrase})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if count >= maxMutedWords {
+		writeError(w, 400, errors.New("too many muted words"), fmt.Sprintf("cannot mute more than %d words", maxMutedWords))
+		return
+	}
+
+	// upsert query. muting a phrase twice updates it
+	mutedWord, err := cfg.db.UpsertMutedWord(r.Context(), database.UpsertMutedWordParams{
+		UserID:    userID,
+		Phrase:    phrase,
+		Action:    action,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when muting word")
+		return
+	}
+	cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID}) // stream.go
+
+	writeJSON(w, 201, newMutedWord(mutedWord))
+}
+
+func (cfg *apiConfig) getMutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// query DB. the list is short enough to not need pages
+	rows, err := cfg.db.GetMutedWords(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting muted words")
+		return
+	}
+
+	// write response
+	responseMutedWords := []MutedWord{}
+	for _, row := range rows {
+		responseMutedWords = append(responseMutedWords, newMutedWord(row))
+	}
+	writeJSON(w, 200, struct {
+		MutedWords []MutedWord `json:"muted_words"`
+	}{responseMutedWords})
+}
+
+func (cfg *apiConfig) deleteMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	mutedWordID, err := uuid.Parse(r.PathValue("mutedWordID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// delete query. muted words of others are not found
+	deleted, err := cfg.db.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+		ID:     mutedWordID,
+		UserID: userID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when unmuting word")
+		return
+	} else if deleted == 0 {
+		writeError(w, 404, errors.New("no such muted word"), "muted word not found")
+		return
+	}
+	cfg.hub.Publish(eventHideChanged, hideChange{UserID: userID})
+
+	writeJSON(w, 204, nil)
+}
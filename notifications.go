@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// notification types
+const (
+	notificationLike      = "like"
+	notificationReply     = "reply"
+	notificationMention   = "mention"
+	notificationFollow    = "follow"
+	notificationRechirp   = "rechirp"
+	notificationChirpyRed = "chirpy_red"
+)
+
+const (
+	maxNotificationActors = 3 // actors listed per notification, the rest only count towards actor_count
+	eventNotification     = "notification"
+)
+
+// Notification tells a user that someone interacted with them. Likes, rechirps and follows are aggregated
+// for as long as the notification is unread, so Actors holds the latest few and ActorCount how many there are.
+type Notification struct {
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"` // when the latest actor was added
+	Type       string       `json:"type"`
+	Message    string       `json:"message"`
+	ChirpID    *uuid.UUID   `json:"chirp_id"` // the liked, rechirped or replied to chirp, or the mentioning chirp
+	Actors     []PublicUser `json:"actors"`
+	ActorCount int64        `json:"actor_count"`
+	Read       bool         `json:"read"`
+	userID     uuid.UUID    // the user being notified, for the hub
+}
+
+// notificationPage is a single page of notifications
+type notificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// notificationMessage is the text a client can show for a notification, like "5 people liked your chirp"
+func notificationMessage(n Notification) string {
+	actor := "Someone"
+	if n.ActorCount > 1 {
+		actor = fmt.Sprintf("%d people", n.ActorCount)
+	} else if len(n.Actors) > 0 && n.Actors[0].DisplayName != "" {
+		actor = n.Actors[0].DisplayName
+	} else if len(n.Actors) > 0 && n.Actors[0].Handle != "" {
+		actor = "@" + n.Actors[0].Handle
+	}
+
+	switch n.Type {
+	case notificationLike:
+		return actor + " liked your chirp"
+	case notificationReply:
+		return actor + " replied to your chirp"
+	case notificationMention:
+		return actor + " mentioned you"
+	case notificationFollow:
+		return actor + " followed you"
+	case notificationRechirp:
+		return actor + " rechirped your chirp"
+	case notificationChirpyRed:
+		return "Welcome to Chirpy Red!"
+	}
+	return ""
+}
+
+// buildNotifications looks up the actors of all notifications at once
+func (cfg *apiConfig) buildNotifications(ctx context.Context, notifications []database.Notification) ([]Notification, error) {
+	responseNotifications := []Notification{}
+	if len(notifications) == 0 {
+		return responseNotifications, nil
+	}
+
+	ids := make([]uuid.UUID, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+	actorRows, err := cfg.db.GetNotificationActors(ctx, database.GetNotificationActorsParams{
+		NotificationIds: ids,
+		MaxActors:       maxNotificationActors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actors := map[uuid.UUID][]PublicUser{}
+	actorCounts := map[uuid.UUID]int64{}
+	for _, row := range actorRows {
+		actors[row.NotificationID] = append(actors[row.NotificationID], newPublicUser(row.User)) // api.go
+		actorCounts[row.NotificationID] = row.ActorCount
+	}
+
+	for _, n := range notifications {
+		responseNotification := Notification{
+			ID:         n.ID,
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			Type:       n.Type,
+			Actors:     []PublicUser{},
+			ActorCount: actorCounts[n.ID],
+			Read:       n.ReadAt.Valid,
+			userID:     n.UserID,
+		}
+		if a, ok := actors[n.ID]; ok {
+			responseNotification.Actors = a
+		}
+		if n.ChirpID.Valid {
+			responseNotification.ChirpID = &n.ChirpID.UUID
+		}
+		responseNotification.Message = notificationMessage(responseNotification)
+		responseNotifications = append(responseNotifications, responseNotification)
+	}
+	return responseNotifications, nil
+}
+
+// notify records that actorID did something that concerns userID, and pushes the notification to userID's
+// live connections. actorID is uuid.Nil for notifications from chirpy itself. Nobody is notified of their own actions,
+// nor of those of users they blocked or muted.
+// Failures are only logged: a missing notification is no reason to fail the request that caused it.
+func (cfg *apiConfig) notify(ctx context.Context, userID, actorID uuid.UUID, notificationType string, chirpID uuid.NullUUID) {
+	if userID == actorID {
+		return
+	}
+	hidden, err := cfg.db.IsHidden(ctx, database.IsHiddenParams{ViewerID: userID, AuthorID: actorID})
+	if err == nil && hidden {
+		return
+	}
+	if err == nil {
+		err = cfg.recordNotification(ctx, userID, actorID, notificationType, chirpID)
+	}
+	if err != nil {
+		log.Printf("error notifying user %s of %s: %s", userID, notificationType, err)
+	}
+}
+
+func (cfg *apiConfig) recordNotification(ctx context.Context, userID, actorID uuid.UUID, notificationType string, chirpID uuid.NullUUID) error {
+	// likes and rechirps of the same chirp and follows are aggregated
+	groupKey := sql.NullString{}
+	switch notificationType {
+	case notificationLike, notificationRechirp:
+		groupKey = sql.NullString{String: notificationType + ":" + chirpID.UUID.String(), Valid: true}
+	case notificationFollow:
+		groupKey = sql.NullString{String: notificationType, Valid: true}
+	}
+
+	notification, err := cfg.db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:   userID,
+		Type:     notificationType,
+		ChirpID:  chirpID,
+		GroupKey: groupKey,
+	})
+	if err != nil {
+		return err
+	}
+	if actorID != uuid.Nil {
+		added, err := cfg.db.AddNotificationActor(ctx, database.AddNotificationActorParams{
+			NotificationID: notification.ID,
+			ActorID:        actorID,
+		})
+		if err != nil {
+			return err
+		}
+		if added == 0 { // e.g. liking the same chirp again after unliking it. nothing new to tell
+			return nil
+		}
+		notification, err = cfg.db.TouchNotification(ctx, notification.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	built, err := cfg.buildNotifications(ctx, []database.Notification{notification})
+	if err != nil {
+		return err
+	}
+	cfg.hub.Publish(eventNotification, built[0]) // stream.go
+	return nil
+}
+
+// notifyChirp sends the notifications for a newly posted chirp: to the author of the chirp it replies to,
+// and to everyone it mentions. Someone who is both only gets the reply notification.
+func (cfg *apiConfig) notifyChirp(ctx context.Context, chirp database.Chirp) {
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	notified := map[uuid.UUID]bool{}
+	if chirp.InReplyTo.Valid {
+		parent, err := cfg.db.GetSingleChirp(ctx, chirp.InReplyTo.UUID)
+		if err != nil {
+			log.Printf("error getting parent of chirp %s: %s", chirp.ID, err)
+		} else {
+			cfg.notify(ctx, parent.UserID, chirp.UserID, notificationReply, chirpID)
+			notified[parent.UserID] = true
+		}
+	}
+
+	mentions, err := cfg.db.GetMentionsForChirps(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		log.Printf("error getting mentions of chirp %s: %s", chirp.ID, err)
+		return
+	}
+	for _, mention := range mentions {
+		if !notified[mention.UserID] {
+			cfg.notify(ctx, mention.UserID, chirp.UserID, notificationMention, chirpID)
+			notified[mention.UserID] = true
+		}
+	}
+}
+
+func (cfg *apiConfig) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	//query parameters
+	unreadOnly := false
+	switch r.URL.Query().Get("unread") {
+	case "", "false":
+	case "true":
+		unreadOnly = true
+	default:
+		writeError(w, 400, errors.New("incorrect query parameter"), "unread value should be either 'true' or 'false'")
+		return
+	}
+	page, err := readPageParams(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+
+	// query DB, most recently updated first
+	notifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      unreadOnly,
+		CursorUpdatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database when getting notifications")
+		return
+	}
+	unreadCount, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when counting notifications")
+		return
+	}
+
+	// write response
+	notifications, nextCursor := paginate(notifications, page, func(n database.Notification) cursor {
+		return cursor{CreatedAt: n.UpdatedAt, ID: n.ID}
+	})
+	responseNotifications, err := cfg.buildNotifications(r.Context(), notifications)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, notificationPage{
+		Notifications: responseNotifications,
+		UnreadCount:   unreadCount,
+		NextCursor:    nextCursor,
+	})
+}
+
+func (cfg *apiConfig) readNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	reqParams := struct {
+		IDs []uuid.UUID `json:"ids"`
+		All bool        `json:"all"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	if !reqParams.All && len(reqParams.IDs) == 0 {
+		writeError(w, 400, errors.New("nothing to mark"), "provide either 'ids' or 'all'")
+		return
+	} else if len(reqParams.IDs) > int(maxPageSize) {
+		writeError(w, 400, errors.New("too many ids"), fmt.Sprintf("cannot mark more than %d notifications at once", maxPageSize))
+		return
+	}
+
+	// update query. IDs of notifications that don't belong to the user are ignored
+	if reqParams.All {
+		err = cfg.db.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		err = cfg.db.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    reqParams.IDs,
+		})
+	}
+	if err != nil {
+		writeError(w, 500, err, "error querying database when marking notifications read")
+		return
+	}
+
+	writeJSON(w, 204, nil)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize int32 = 20
+	maxPageSize     int32 = 100
+)
+
+// cursor points at the last row of a page in a keyset-paginated listing.
+// Listings are ordered on (created_at, id) so the pair is always unique.
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func encodeCursor(c cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, err
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return cursor{}, err
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return cursor{}, err
+	}
+	return cursor{CreatedAt: t, ID: u}, nil
+}
+
+// pageParams holds the `limit` and `cursor` query parameters of a listing request
+type pageParams struct {
+	limit  int32
+	cursor *cursor
+}
+
+func readPageParams(r *http.Request) (pageParams, error) {
+	limit, err := readLimit(r)
+	if err != nil {
+		return pageParams{}, err
+	}
+	p := pageParams{limit: limit}
+
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		decoded, err := decodeCursor(c)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		p.cursor = &decoded
+	}
+
+	return p, nil
+}
+
+// readLimit parses the `limit` query parameter on its own, for listings that don't use a keyset cursor
+func readLimit(r *http.Request) (int32, error) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 1 || limit > int(maxPageSize) {
+		return 0, errors.New("limit must be a number between 1 and 100")
+	}
+	return int32(limit), nil
+}
+
+// offset cursors are for listings that are not ordered on (created_at, id), like ranked search results.
+// they are just as opaque to clients as keyset cursors.
+func encodeOffsetCursor(offset int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset|" + strconv.Itoa(int(offset))))
+}
+
+func decodeOffsetCursor(s string) (int32, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	prefix, offset, found := strings.Cut(string(raw), "|")
+	if !found || prefix != "offset" {
+		return 0, errors.New("malformed cursor")
+	}
+	o, err := strconv.ParseInt(offset, 10, 32)
+	if err != nil || o < 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	return int32(o), nil
+}
+
+// fetchSize is the number of rows to query: one more than the limit so we know whether a next page exists
+func (p pageParams) fetchSize() int32 {
+	return p.limit + 1
+}
+
+func (p pageParams) cursorCreatedAt() sql.NullTime {
+	if p.cursor == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.cursor.CreatedAt, Valid: true}
+}
+
+func (p pageParams) cursorID() uuid.NullUUID {
+	if p.cursor == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.cursor.ID, Valid: true}
+}
+
+// paginate trims the extra row fetched by fetchSize and returns the cursor for the next page,
+// or an empty string if this is the last page.
+func paginate[T any](rows []T, p pageParams, key func(T) cursor) ([]T, string) {
+	if len(rows) <= int(p.limit) {
+		return rows, ""
+	}
+	rows = rows[:p.limit]
+	return rows, encodeCursor(key(rows[len(rows)-1]))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Profile is the public profile of a user
+type Profile struct {
+	PublicUser
+	ChirpCount     int64 `json:"chirp_count"`
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+}
+
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	//users can be looked up by either their ID or their handle. handles can never be valid UUIDs as they are too short
+	handleOrID := r.PathValue("handleOrID")
+	var user database.User
+	var err error
+	if userID, parseErr := uuid.Parse(handleOrID); parseErr == nil {
+		user, err = cfg.db.GetUserByID(r.Context(), userID)
+	} else {
+		user, err = cfg.db.GetUserByHandle(r.Context(), handleOrID)
+	}
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+
+	// counts
+	stats, err := cfg.db.GetUserProfileStats(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database for profile counts")
+		return
+	}
+
+	// write response
+	profile := Profile{
+		PublicUser:     newPublicUser(user), // api.go
+		ChirpCount:     stats.ChirpCount,
+		FollowerCount:  stats.FollowerCount,
+		FollowingCount: stats.FollowingCount,
+	}
+	writeJSON(w, 200, profile)
+}
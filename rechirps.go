@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+	if _, ok := cfg.requireActiveUser(w, r, userID); !ok { // moderation.go
+		return
+	}
+
+	// check if chirp exists. rechirping a rechirp rechirps the original
+	original, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && original.DeletedAt.Valid) {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	if original.RechirpOf.Valid {
+		original, err = cfg.db.GetSingleChirp(r.Context(), original.RechirpOf.UUID)
+		if err != nil {
+			writeError(w, 500, err, "error querying database")
+			return
+		}
+	}
+	if !cfg.requireNotBlocked(w, r, userID, original.UserID) { // blocks.go
+		return
+	}
+
+	// rechirp query
+	respCode := 201
+	rechirpParams := database.CreateRechirpParams{
+		UserID:    userID,
+		RechirpOf: original.ID,
+	}
+	rechirp, err := cfg.db.CreateRechirp(r.Context(), rechirpParams)
+	if err == sql.ErrNoRows { // already rechirped, so return that one instead
+		respCode = 200
+		rechirp, err = cfg.db.GetRechirp(r.Context(), database.GetRechirpParams(rechirpParams))
+	}
+	if err != nil {
+		writeError(w, 500, err, "error querying database when rechirping")
+		return
+	}
+	if respCode == 201 {
+		cfg.publishChirp(r.Context(), rechirp) // stream.go
+		originalID := uuid.NullUUID{UUID: original.ID, Valid: true}
+		cfg.notify(r.Context(), original.UserID, userID, notificationRechirp, originalID) // notifications.go
+	}
+
+	// write response
+	responseChirp, err := cfg.buildChirp(r.Context(), rechirp, userID) // api.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, respCode, responseChirp)
+}
+
+func (cfg *apiConfig) undoRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// find the user's rechirp of this chirp
+	rechirp, err := cfg.db.GetRechirp(r.Context(), database.GetRechirpParams{
+		UserID:    userID,
+		RechirpOf: chirpID,
+	})
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "rechirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+
+	// delete it like any other chirp
+	_, err = cfg.db.DeleteSingleChirp(r.Context(), database.DeleteSingleChirpParams{
+		ID:     rechirp.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	cfg.publishChirpDeleted(rechirp) // stream.go
+
+	writeJSON(w, 204, nil)
+}
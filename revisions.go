@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ChirpRevision is an earlier version of an edited chirp
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`  // when this version was posted
+	ReplacedAt time.Time `json:"replaced_at"` // when it was edited into the next version
+	Body       string    `json:"body"`
+}
+
+func (cfg *apiConfig) patchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	reqParams := struct {
+		Body string `json:"body"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// check if chirp exists and belongs to the user
+	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	} else if chirp.UserID != userID {
+		writeError(w, 403, errors.New("wrong user ID"), "user not authorized to edit chirp")
+		return
+	}
+	if chirp.RechirpOf.Valid {
+		writeError(w, 400, errors.New("chirp is a rechirp"), "rechirps cannot be edited")
+		return
+	}
+
+	// same checks as a new chirp
+	user, ok := cfg.requireActiveUser(w, r, userID) // moderation.go
+	if !ok {
+		return
+	}
+	body, flaggedWords, err := cfg.cleanChirpBody(reqParams.Body, cfg.maxChirpLengthFor(user)) // api.go
+	if err != nil {
+		writeChirpBodyError(w, err)
+		return
+	}
+
+	// update query. this also stores the current body as a revision
+	updatedChirp, err := cfg.db.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:     chirpID,
+		UserID: userID,
+		Body:   body,
+	})
+	if err == sql.ErrNoRows { // deleted in the meantime
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error updating chirp")
+		return
+	}
+	cfg.indexChirp(r.Context(), updatedChirp)                 // api.go
+	cfg.flagChirp(r.Context(), updatedChirp.ID, flaggedWords) // filter.go
+
+	// write response
+	responseChirp, err := cfg.buildChirp(r.Context(), updatedChirp, userID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, responseChirp)
+}
+
+func (cfg *apiConfig) getRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// check if chirp exists
+	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	if !cfg.requireVisibleChirp(w, r, chirp, cfg.optionalUserID(r)) { // api.go, auth.go
+		return
+	}
+
+	// query DB
+	revisions, err := cfg.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database for revisions")
+		return
+	}
+
+	// write response, newest revision first
+	responseRevisions := []ChirpRevision{}
+	for _, revision := range revisions {
+		responseRevisions = append(responseRevisions, ChirpRevision{
+			ID:         revision.ID,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+			Body:       revision.Body,
+		})
+	}
+	writeJSON(w, 200, responseRevisions)
+}
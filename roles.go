@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/auth"
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// user roles. every role can do everything the roles before it can
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{roleUser: 0, roleModerator: 1, roleAdmin: 2}
+
+// hasRole reports whether role is the given role or one above it. unknown roles, like the empty role of tokens
+// made before roles existed, have no rights at all
+func hasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+type staffUserKey struct{}
+
+// staffUser returns the user that middlewareRequireRole let through
+func staffUser(r *http.Request) database.User {
+	user, _ := r.Context().Value(staffUserKey{}).(database.User)
+	return user
+}
+
+// middlewareRequireRole only lets requests through from users with at least the given role. The role claim in the
+// access token turns everyone else away without a database query; for everyone else the role is checked against
+// the database as well, so demoted and suspended users lose access right away instead of when their token expires.
+// Handlers behind it get the user from staffUser. Nesting it only queries the database once.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(staffUserKey{}).(database.User)
+		if !ok {
+			token, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				writeError(w, 401, err, "user not authorized")
+				return
+			}
+			userID, claimedRole, err := auth.ValidateJWTRole(token, cfg.secret)
+			if err != nil {
+				writeError(w, 401, err, "user not authorized")
+				return
+			}
+			if !hasRole(claimedRole, role) {
+				writeError(w, 403, errors.New("role claim too low"), "user not allowed to access this endpoint")
+				return
+			}
+			user, err = cfg.db.GetUserByID(r.Context(), userID)
+			if err == sql.ErrNoRows {
+				writeError(w, 401, err, "user not authorized")
+				return
+			} else if err != nil {
+				writeError(w, 500, err, "error querying database")
+				return
+			}
+			if user.SuspendedAt.Valid {
+				writeError(w, 403, errors.New("user suspended"), "account is suspended")
+				return
+			}
+		}
+		if !hasRole(user.Role, role) {
+			writeError(w, 403, errors.New("role too low"), "user not allowed to access this endpoint")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), staffUserKey{}, user)))
+	})
+}
+
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+	reqParams := struct {
+		Role string `json:"role"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	if _, ok := roleRanks[reqParams.Role]; !ok {
+		writeError(w, 400, errors.New("unknown role"), "role should be 'user', 'moderator' or 'admin'")
+		return
+	}
+	// so there is always at least one admin left
+	if userID == staffUser(r).ID {
+		writeError(w, 400, errors.New("own role"), "admins cannot change their own role")
+		return
+	}
+
+	// update query
+	var user database.User
+	details := map[string]any{"to": reqParams.Role}
+	err = cfg.audited(r, staffUser(r).ID, auditSetRole, userID, details, func(q *database.Queries) error {
+		previous, err := q.GetUserByID(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		details["from"] = previous.Role
+		user, err = q.SetUserRole(r.Context(), database.SetUserRoleParams{ID: userID, Role: reqParams.Role})
+		return err
+	}) // audit.go
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when setting role")
+		return
+	}
+
+	writeJSON(w, 200, struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}{user.ID, user.Role})
+}
+
+// bootstrapAdminHandler makes the first admin. It needs ADMIN_BOOTSTRAP_TOKEN and stops working as soon as there is an admin.
+func (cfg *apiConfig) bootstrapAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.adminBootstrapToken == "" {
+		writeError(w, 404, nil, "404 page not found")
+		return
+	}
+
+	// tokenomics
+	userID, err := cfg.requestUserID(r) // auth.go
+	if err != nil {
+		writeError(w, 401, err, "user not authorized")
+		return
+	}
+
+	// read request
+	reqParams := struct {
+		BootstrapToken string `json:"bootstrap_token"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		writeError(w, 400, err, "request has incorrect JSON structure")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(reqParams.BootstrapToken), []byte(cfg.adminBootstrapToken)) != 1 {
+		writeError(w, 403, errors.New("wrong bootstrap token"), "incorrect bootstrap token")
+		return
+	}
+
+	// update query
+	errAdminExists := errors.New("admin exists")
+	err = cfg.audited(r, userID, auditBootstrapAdmin, userID, nil, func(q *database.Queries) error {
+		updated, err := q.BootstrapAdmin(r.Context(), userID)
+		if err == nil && updated == 0 {
+			err = errAdminExists
+		}
+		return err
+	}) // audit.go
+	if err == errAdminExists {
+		writeError(w, 409, err, "there already is an admin")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database when creating admin")
+		return
+	}
+
+	// write response with a fresh access token that carries the new role
+	token, err := auth.MakeJWTWithRole(userID, roleAdmin, cfg.secret)
+	if err != nil {
+		writeError(w, 500, err, "error creating JWT")
+		return
+	}
+	writeJSON(w, 200, struct {
+		ID    uuid.UUID `json:"id"`
+		Role  string    `json:"role"`
+		Token string    `json:"token"`
+	}{userID, roleAdmin, token})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxSearchQueryLength = 256
+
+// parseSearchTime accepts either a full RFC 3339 timestamp or just a date
+func parseSearchTime(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return sql.NullTime{}, err
+		}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+// parseSearchUntil is parseSearchTime for the end of a range, which is exclusive. A date includes that whole day.
+func parseSearchUntil(value string) (sql.NullTime, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return sql.NullTime{Time: t.AddDate(0, 0, 1), Valid: true}, nil
+	}
+	return parseSearchTime(value)
+}
+
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	//query parameters
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, 400, errors.New("missing query parameter"), "search query 'q' is required")
+		return
+	} else if len(q) > maxSearchQueryLength {
+		writeError(w, 400, errors.New("query too long"), "search query cannot exceed 256 characters")
+		return
+	}
+	searchParams := database.SearchChirpsParams{Query: q}
+	if authorID := r.URL.Query().Get("author_id"); authorID != "" {
+		userID, err := uuid.Parse(authorID)
+		if err != nil {
+			writeError(w, 400, err, "invalid author ID provided")
+			return
+		}
+		searchParams.AuthorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	var err error
+	searchParams.Since, err = parseSearchTime(r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, 400, err, "since should be a date (2006-01-02) or an RFC 3339 timestamp")
+		return
+	}
+	searchParams.Until, err = parseSearchUntil(r.URL.Query().Get("until"))
+	if err != nil {
+		writeError(w, 400, err, "until should be a date (2006-01-02) or an RFC 3339 timestamp")
+		return
+	}
+	//pagination. results are ranked, so this uses an offset cursor rather than a keyset one
+	limit, err := readLimit(r) // pagination.go
+	if err != nil {
+		writeError(w, 400, err, err.Error())
+		return
+	}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		searchParams.PageOffset, err = decodeOffsetCursor(c)
+		if err != nil {
+			writeError(w, 400, err, "invalid cursor")
+			return
+		}
+	}
+	searchParams.PageSize = limit + 1
+	viewerID := cfg.optionalUserID(r) // auth.go
+	searchParams.ViewerID = viewerID
+
+	// query DB
+	rows, err := cfg.db.SearchChirps(r.Context(), searchParams)
+	if err != nil {
+		writeError(w, 500, err, "error querying database when searching chirps")
+		return
+	}
+
+	// write response in the same shape as GET /api/chirps
+	nextCursor := ""
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		nextCursor = encodeOffsetCursor(searchParams.PageOffset + limit)
+	}
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	responseChirps, err := cfg.buildChirps(r.Context(), chirps, viewerID) // api.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.hideMutedWords(r.Context(), responseChirps, viewerID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	writeJSON(w, 200, chirpPage{Chirps: responseChirps, NextCursor: nextCursor})
+}
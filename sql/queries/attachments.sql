@@ -0,0 +1,32 @@
+-- name: CreateAttachment :one
+INSERT INTO chirp_attachments (id, created_at, chirp_id, position, content_type, size_bytes, width, height, blob_key, thumbnail_key)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetAttachmentsForChirps :many
+SELECT * FROM chirp_attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteChirpAttachments :many
+-- returns the deleted rows so their blobs can be removed as well
+DELETE FROM chirp_attachments
+WHERE chirp_id = $1
+RETURNING *;
+
+-- name: GetUserAttachments :many
+-- every attachment of the user's chirps, so their images can be removed along with them
+SELECT chirp_attachments.* FROM chirp_attachments
+JOIN chirps ON chirps.id = chirp_attachments.chirp_id
+WHERE chirps.user_id = $1;
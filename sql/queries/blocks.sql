@@ -0,0 +1,83 @@
+-- name: CreateBlock :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+-- whether either user blocked the other
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = sqlc.arg(other_user_id))
+        OR (blocker_id = sqlc.arg(other_user_id) AND blocked_id = sqlc.arg(user_id))
+) AS blocked;
+
+-- name: IsBlockedByAny :one
+-- whether the user and any of the others blocked one another
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = ANY(sqlc.arg(other_user_ids)::uuid[]))
+        OR (blocker_id = ANY(sqlc.arg(other_user_ids)::uuid[]) AND blocked_id = sqlc.arg(user_id))
+) AS blocked;
+
+-- name: IsBlockedInDirectConversation :one
+-- whether the user and the other participant of a one-to-one conversation blocked one another.
+-- always false for group conversations
+SELECT EXISTS (
+    SELECT 1 FROM conversations
+    JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+    JOIN blocks ON (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = conversation_participants.user_id)
+        OR (blocks.blocker_id = conversation_participants.user_id AND blocks.blocked_id = sqlc.arg(user_id))
+    WHERE conversations.id = sqlc.arg(conversation_id)
+        AND conversations.direct_key IS NOT NULL
+) AS blocked;
+
+-- name: GetBlockedUsers :many
+SELECT sqlc.embed(users), blocks.created_at AS blocked_at FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = sqlc.arg(user_id)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (blocks.created_at, users.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY blocks.created_at DESC, users.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (muter_id, muted_id) DO NOTHING;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT sqlc.embed(users), mutes.created_at AS muted_at FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = sqlc.arg(user_id)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (mutes.created_at, users.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY mutes.created_at DESC, users.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: IsHidden :one
+-- whether chirps and notifications from author_id are hidden from viewer_id, see hidden_from in the schema
+SELECT hidden_from(sqlc.arg(viewer_id)::uuid, sqlc.arg(author_id)::uuid)::boolean AS hidden;
+
+-- name: GetHiddenUserIDs :many
+-- everyone whose chirps are hidden from the user, for filtering live events
+SELECT blocks.blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = sqlc.arg(user_id)
+UNION
+SELECT blockers.blocker_id FROM blocks blockers WHERE blockers.blocked_id = sqlc.arg(user_id)
+UNION
+SELECT mutes.muted_id FROM mutes WHERE mutes.muter_id = sqlc.arg(user_id);
@@ -1,28 +1,158 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 
+-- name: CreateRechirp :one
+-- a plain rechirp is a chirp without a body of its own. returns no rows if the user already rechirped the chirp
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    sqlc.arg(user_id),
+    sqlc.arg(rechirp_of)::uuid
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND rechirp_of = sqlc.arg(rechirp_of)::uuid AND deleted_at IS NULL;
+
 -- name: GetChirps :many
 SELECT * FROM chirps
-ORDER BY created_at ASC;
+WHERE deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetChirpsByID :many
 SELECT * FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC;
+WHERE user_id = sqlc.arg(user_id)
+    AND deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetChirpsByIDDesc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+    AND deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, user_id, rechirp_of)
+    AND hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetSingleChirp :one
 SELECT * FROM chirps
 WHERE id = $1;
 
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
 -- name: DeleteSingleChirp :one
-DELETE FROM chirps
-WHERE id = $1 AND user_id = $2
+-- chirps are never removed outright but left as a tombstone, so replies to them keep their place in the thread.
+-- earlier versions of the chirp go with it.
+WITH revisions AS (
+    DELETE FROM chirp_revisions
+    WHERE chirp_id IN (SELECT c.id FROM chirps c WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NULL)
+)
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.user_id = $2 AND chirps.deleted_at IS NULL
+RETURNING *;
+
+-- name: UpdateChirpBody :one
+-- the current body is copied into chirp_revisions in the same statement, so no version is ever lost
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, created_at, replaced_at, chirp_id, body)
+    SELECT gen_random_uuid(), COALESCE(c.edited_at, c.created_at), NOW(), c.id, c.body FROM chirps c
+    WHERE c.id = sqlc.arg(id) AND c.user_id = sqlc.arg(user_id) AND c.deleted_at IS NULL
+    FOR UPDATE
+)
+UPDATE chirps
+SET body = sqlc.arg(body), edited_at = NOW(), updated_at = NOW()
+WHERE chirps.id = sqlc.arg(id) AND chirps.user_id = sqlc.arg(user_id) AND chirps.deleted_at IS NULL
 RETURNING *;
+
+-- name: GetTimeline :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg(user_id)
+    AND chirps.deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(user_id), chirps.user_id, chirps.rechirp_of)
+    AND chirps.hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetChirpAncestors :many
+-- returns the chain of chirps the given chirp replies to, root first.
+-- the chain stops below the first chirp hidden from the viewer, like replies do in GetChirpDescendants
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to, 1 AS depth FROM chirps parent
+    WHERE parent.id = (SELECT child.in_reply_to FROM chirps child WHERE child.id = sqlc.arg(chirp_id)::uuid)
+        AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, parent.user_id, parent.rechirp_of)
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1 FROM chirps parent
+    JOIN ancestors ON parent.id = ancestors.in_reply_to
+    WHERE ancestors.depth < sqlc.arg(max_depth)::int
+        AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, parent.user_id, parent.rechirp_of)
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON ancestors.id = chirps.id
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+-- returns every reply below the given chirp, oldest first so parents come before their replies.
+-- replies hidden from the viewer are left out together with everything below them
+WITH RECURSIVE descendants AS (
+    SELECT reply.id, 1 AS depth FROM chirps reply
+    WHERE reply.in_reply_to = sqlc.arg(chirp_id)::uuid
+        AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, reply.user_id, reply.rechirp_of)
+    UNION ALL
+    SELECT reply.id, descendants.depth + 1 FROM chirps reply
+    JOIN descendants ON reply.in_reply_to = descendants.id
+    WHERE descendants.depth < sqlc.arg(max_depth)::int
+        AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, reply.user_id, reply.rechirp_of)
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON descendants.id = chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(max_replies);
+
+-- name: CountReplies :many
+SELECT in_reply_to::uuid AS chirp_id, COUNT(*) AS reply_count FROM chirps
+WHERE in_reply_to = ANY(sqlc.arg(chirp_ids)::uuid[])
+    AND deleted_at IS NULL
+GROUP BY in_reply_to;
@@ -0,0 +1,34 @@
+-- name: GetFilterWords :many
+SELECT * FROM filter_words
+ORDER BY word ASC;
+
+-- name: AddFilterWord :execrows
+INSERT INTO filter_words (word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT (word) DO NOTHING;
+
+-- name: DeleteFilterWord :execrows
+DELETE FROM filter_words
+WHERE word = $1;
+
+-- name: FlagChirp :exec
+-- flagging a chirp again, like after an edit, replaces the words it was flagged for
+INSERT INTO chirp_flags (chirp_id, created_at, words)
+VALUES (
+    sqlc.arg(chirp_id),
+    NOW(),
+    sqlc.arg(words)::text[]
+)
+ON CONFLICT (chirp_id) DO UPDATE SET created_at = EXCLUDED.created_at, words = EXCLUDED.words;
+
+-- name: GetFlaggedChirps :many
+SELECT sqlc.embed(chirps), chirp_flags.words, chirp_flags.created_at AS flagged_at FROM chirp_flags
+JOIN chirps ON chirps.id = chirp_flags.chirp_id
+WHERE chirps.deleted_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirp_flags.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY chirp_flags.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,40 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowers :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg(user_id)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (follows.created_at, users.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetFollowing :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg(user_id)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (follows.created_at, users.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
+
+-- name: DeleteFollowsBetween :exec
+-- removes follows in both directions, for when one user blocks the other
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_id) AND followee_id = sqlc.arg(other_user_id))
+    OR (follower_id = sqlc.arg(other_user_id) AND followee_id = sqlc.arg(user_id));
@@ -0,0 +1,51 @@
+-- name: UpsertHashtag :one
+INSERT INTO hashtags (id, created_at, tag)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1
+)
+ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING *;
+
+-- name: AddChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (chirp_id, hashtag_id) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.tag = sqlc.arg(tag)
+    AND chirps.deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, chirps.user_id, chirps.rechirp_of)
+    AND chirps.hidden_at IS NULL
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetTrendingHashtags :many
+-- ranks tags by the number of chirps posted since the start of the window. chirp_hashtags rows are remade when a
+-- chirp is edited, so their created_at would put old chirps back in the window. chirps hidden by a moderator or by
+-- suspended users do not count
+SELECT hashtags.tag, COUNT(*) AS chirp_count, COUNT(DISTINCT chirps.user_id) AS user_count FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+    AND chirps.deleted_at IS NULL
+    AND chirps.hidden_at IS NULL
+JOIN users ON users.id = chirps.user_id
+    AND users.suspended_at IS NULL
+WHERE chirps.created_at >= sqlc.arg(since)
+GROUP BY hashtags.tag
+ORDER BY chirp_count DESC, user_count DESC, hashtags.tag ASC
+LIMIT sqlc.arg(max_tags);
@@ -0,0 +1,18 @@
+-- name: CreateLike :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteLike :exec
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetLikeStats :many
+-- like counts for a batch of chirps, and whether the viewer is one of the likers
+SELECT chirp_id, COUNT(*) AS like_count, BOOL_OR(user_id = sqlc.arg(viewer_id)::uuid)::boolean AS liked_by_viewer FROM likes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id;
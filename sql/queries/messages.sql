@@ -0,0 +1,97 @@
+-- name: CreateConversation :one
+-- returns no rows if there already is a one-to-one conversation with the same direct_key
+INSERT INTO conversations (id, created_at, updated_at, created_by, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (conversation_id, user_id) DO NOTHING;
+
+-- name: IsConversationParticipant :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_participants
+    WHERE conversation_id = $1 AND user_id = $2
+);
+
+-- name: GetConversationsForUser :many
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(cursor_updated_at)::timestamp IS NULL
+    OR (conversations.updated_at, conversations.id) < (sqlc.narg(cursor_updated_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetConversationParticipants :many
+SELECT conversation_participants.conversation_id, conversation_participants.last_read_at, sqlc.embed(users) FROM conversation_participants
+JOIN users ON users.id = conversation_participants.user_id
+WHERE conversation_participants.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY conversation_participants.conversation_id, conversation_participants.joined_at, users.id;
+
+-- name: GetLastMessages :many
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY conversation_id, created_at DESC, id DESC;
+
+-- name: CountUnreadMessages :many
+-- messages from others sent after the user last read the conversation
+SELECT messages.conversation_id, COUNT(*) AS unread_count FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+    AND conversation_participants.user_id = sqlc.arg(user_id)
+WHERE messages.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+    AND messages.sender_id != sqlc.arg(user_id)
+    AND (conversation_participants.last_read_at IS NULL OR messages.created_at > conversation_participants.last_read_at)
+GROUP BY messages.conversation_id;
+
+-- name: CreateMessage :one
+-- also moves the conversation to the top of the list and marks it read for the sender
+WITH touched AS (
+    UPDATE conversations SET updated_at = NOW()
+    WHERE conversations.id = sqlc.arg(conversation_id)
+), marked_read AS (
+    UPDATE conversation_participants SET last_read_at = NOW()
+    WHERE conversation_participants.conversation_id = sqlc.arg(conversation_id)
+        AND conversation_participants.user_id = sqlc.arg(sender_id)
+)
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg(conversation_id),
+    sqlc.arg(sender_id),
+    sqlc.arg(body)
+)
+RETURNING *;
+
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
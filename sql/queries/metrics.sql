@@ -0,0 +1,11 @@
+-- name: GetRequestCounts :many
+SELECT * FROM request_counts;
+
+-- name: AddRequestCount :exec
+INSERT INTO request_counts (route, method, status_code, count, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (route, method, status_code)
+DO UPDATE SET count = request_counts.count + EXCLUDED.count, updated_at = NOW();
+
+-- name: ResetRequestCounts :exec
+DELETE FROM request_counts;
@@ -0,0 +1,80 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, user_id, chirp_id, chirp_body, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReports :many
+-- the moderation queue, oldest first. every filter is optional
+SELECT * FROM reports
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+    AND (sqlc.narg(reason)::text IS NULL OR reason = sqlc.narg(reason)::text)
+    AND (sqlc.narg(target)::text IS NULL
+    OR (sqlc.narg(target)::text = 'chirp' AND chirp_id IS NOT NULL)
+    OR (sqlc.narg(target)::text = 'user' AND chirp_id IS NULL))
+    AND (sqlc.narg(assignee_id)::uuid IS NULL OR assignee_id = sqlc.narg(assignee_id)::uuid)
+    AND (NOT sqlc.arg(unassigned)::boolean OR assignee_id IS NULL)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: AssignReport :one
+UPDATE reports
+SET assignee_id = sqlc.narg(assignee_id), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ResolveReport :one
+-- returns no rows if the report was already resolved or dismissed
+UPDATE reports
+SET status = sqlc.arg(status), resolved_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND status = 'open'
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, report_id, user_id, chirp_id, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetModerationActions :many
+-- newest first, optionally only those about a single report
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg(report_id)::uuid IS NULL OR report_id = sqlc.narg(report_id)::uuid)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
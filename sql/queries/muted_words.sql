@@ -0,0 +1,31 @@
+-- name: UpsertMutedWord :one
+-- muting a phrase again replaces its action and expiry, also when the earlier one had expired
+INSERT INTO muted_words (id, created_at, user_id, phrase, action, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, phrase) DO UPDATE SET action = EXCLUDED.action, expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: GetMutedWords :many
+-- the user's muted words that have not expired yet, oldest first
+SELECT * FROM muted_words
+WHERE user_id = $1
+    AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC, id ASC;
+
+-- name: CountMutedWords :one
+-- the user's muted words other than the given phrase, so muting a phrase again works with a full list
+SELECT COUNT(*) FROM muted_words
+WHERE user_id = $1
+    AND phrase <> $2
+    AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2;
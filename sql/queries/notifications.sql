@@ -0,0 +1,65 @@
+-- name: UpsertNotification :one
+-- returns the unread notification with the same group key instead of creating a new one, if there is one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, chirp_id, group_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = notifications.updated_at
+RETURNING *;
+
+-- name: AddNotificationActor :execrows
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (notification_id, actor_id) DO NOTHING;
+
+-- name: TouchNotification :one
+UPDATE notifications SET updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetNotifications :many
+-- notifications about chirps that have since been deleted are left out
+SELECT * FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id)
+    AND (NOT sqlc.arg(unread_only)::boolean OR notifications.read_at IS NULL)
+    AND NOT EXISTS (SELECT 1 FROM chirps WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NOT NULL)
+    AND (sqlc.narg(cursor_updated_at)::timestamp IS NULL
+    OR (notifications.updated_at, notifications.id) < (sqlc.narg(cursor_updated_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetNotificationActors :many
+-- the most recent actors of each notification, at most max_actors per notification, with the total number of actors
+SELECT ranked.notification_id, ranked.actor_count, sqlc.embed(users) FROM (
+    SELECT notification_actors.notification_id, notification_actors.actor_id, notification_actors.created_at,
+        ROW_NUMBER() OVER (PARTITION BY notification_actors.notification_id ORDER BY notification_actors.created_at DESC) AS position,
+        COUNT(*) OVER (PARTITION BY notification_actors.notification_id) AS actor_count
+    FROM notification_actors
+    WHERE notification_actors.notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+) AS ranked
+JOIN users ON users.id = ranked.actor_id
+WHERE ranked.position <= sqlc.arg(max_actors)::bigint
+ORDER BY ranked.notification_id, ranked.created_at DESC;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND read_at IS NULL AND id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
@@ -0,0 +1,17 @@
+-- name: SearchChirps :many
+-- full-text search using web search syntax: "quoted phrases", OR and -excluded words.
+-- results are ranked by relevance, newest first for equal ranks. the vectors are kept up to date by a trigger, see
+-- 015_chirps_search.sql.
+SELECT sqlc.embed(chirps), ts_rank(chirp_search_vectors.search_vector, query)::real AS rank
+FROM chirps
+JOIN chirp_search_vectors ON chirp_search_vectors.chirp_id = chirps.id,
+    websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE chirp_search_vectors.search_vector @@ query
+    AND chirps.deleted_at IS NULL
+    AND NOT chirp_hidden_from(sqlc.arg(viewer_id)::uuid, chirps.user_id, chirps.rechirp_of)
+    AND chirps.hidden_at IS NULL
+    AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+    AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since)::timestamp)
+    AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until)::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
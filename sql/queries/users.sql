@@ -1,12 +1,15 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    FALSE
+    FALSE,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
@@ -27,4 +30,82 @@ RETURNING *;
 UPDATE users
 SET is_chirpy_red = TRUE, updated_at = NOW()
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+
+-- name: UpdateProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(sqlc.arg(handle));
+
+-- name: GetUserProfileStats :one
+SELECT
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = sqlc.arg(user_id)::uuid AND chirps.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = sqlc.arg(user_id)::uuid) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = sqlc.arg(user_id)::uuid) AS following_count;
+
+-- name: GetUsersByHandles :many
+-- handles are case insensitive, so the given handles have to be lowercased already
+SELECT * FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg(handles)::text[]);
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BootstrapAdmin :execrows
+-- makes the user an admin, but only as long as there is no admin yet
+UPDATE users
+SET role = 'admin', updated_at = NOW()
+WHERE users.id = sqlc.arg(id)
+AND NOT EXISTS (SELECT 1 FROM users AS admins WHERE admins.role = 'admin');
+
+-- name: ListUsers :many
+-- search matches part of the email, handle or display name. its LIKE wildcards have to be escaped already
+SELECT * FROM users
+WHERE (sqlc.narg(search)::text IS NULL
+    OR email ILIKE '%' || sqlc.narg(search)::text || '%'
+    OR handle ILIKE '%' || sqlc.narg(search)::text || '%'
+    OR display_name ILIKE '%' || sqlc.narg(search)::text || '%')
+    AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role)::text)
+    AND (sqlc.narg(suspended)::boolean IS NULL OR (suspended_at IS NOT NULL) = sqlc.narg(suspended)::boolean)
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetChirpyRed :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :execrows
+-- everything the user made goes with them, see the ON DELETE clauses in the schema
+DELETE FROM users
+WHERE id = $1;
+
+-- name: CountOpenReportsAgainstUser :one
+SELECT COUNT(*) FROM reports
+WHERE user_id = $1 AND status = 'open';
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID REFERENCES chirps (id) ON DELETE CASCADE,
+ADD COLUMN quoted_chirp_id UUID REFERENCES chirps (id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_user_id_rechirp_of_idx;
+ALTER TABLE chirps
+DROP COLUMN quoted_chirp_id,
+DROP COLUMN rechirp_of;
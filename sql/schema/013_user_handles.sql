@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT;
+CREATE UNIQUE INDEX users_lower_handle_idx ON users (LOWER(handle));
+
+-- +goose Down
+DROP INDEX users_lower_handle_idx;
+ALTER TABLE users
+DROP COLUMN handle;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- the stored search vector lives next to chirps rather than in it, so SELECT * FROM chirps does not read it.
+-- a trigger keeps it in step with the body
+CREATE TABLE chirp_search_vectors (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps (id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+CREATE INDEX chirp_search_vectors_search_vector_idx ON chirp_search_vectors USING GIN (search_vector);
+
+-- +goose StatementBegin
+CREATE FUNCTION chirps_update_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO chirp_search_vectors (chirp_id, search_vector)
+    VALUES (NEW.id, to_tsvector('english', NEW.body))
+    ON CONFLICT (chirp_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_search_vector
+AFTER INSERT OR UPDATE OF body ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_search_vector();
+
+INSERT INTO chirp_search_vectors (chirp_id, search_vector)
+SELECT id, to_tsvector('english', body) FROM chirps;
+
+-- +goose Down
+DROP TRIGGER chirps_search_vector ON chirps;
+DROP FUNCTION chirps_update_search_vector();
+DROP TABLE chirp_search_vectors;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (blocker_id != blocked_id)
+);
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (muter_id != muted_id)
+);
+
+-- whether chirps and notifications from author_id are hidden from viewer_id: when either blocked the other,
+-- or the viewer muted the author. feed queries pass uuid.Nil as viewer for anonymous requests, which hides nothing.
+-- +goose StatementBegin
+CREATE FUNCTION hidden_from(viewer_id UUID, author_id UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = author_id)
+            OR (blocks.blocker_id = author_id AND blocks.blocked_id = viewer_id)
+    ) OR EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = viewer_id AND mutes.muted_id = author_id
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- idem for a chirp, which is also hidden when it rechirps a chirp by someone hidden from the viewer
+-- +goose StatementBegin
+CREATE FUNCTION chirp_hidden_from(viewer_id UUID, author_id UUID, rechirp_of UUID) RETURNS BOOLEAN AS $$
+    SELECT hidden_from(viewer_id, author_id) OR EXISTS (
+        SELECT 1 FROM chirps original
+        WHERE original.id = rechirp_of AND hidden_from(viewer_id, original.user_id)
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_hidden_from;
+DROP FUNCTION hidden_from;
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE muted_words (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    -- lowercased with whitespace collapsed, see strutils.NormalizePhrase
+    phrase TEXT NOT NULL,
+    -- hide leaves matching chirps out of feeds, collapse only marks them
+    action TEXT NOT NULL CHECK (action IN ('hide', 'collapse')),
+    expires_at TIMESTAMP,
+    UNIQUE (user_id, phrase),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE muted_words;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- words censored in chirps, see strutils.Filter. more can be added from a file with FILTER_WORDS_FILE
+CREATE TABLE filter_words (
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+INSERT INTO filter_words (word, created_at)
+VALUES ('kerfuffle', NOW()), ('sharbert', NOW()), ('fornax', NOW());
+
+-- chirps that contained filtered words while FILTER_MODE was flag, for moderators to look at
+CREATE TABLE chirp_flags (
+    chirp_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    words TEXT[] NOT NULL,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE filter_words;
@@ -0,0 +1,108 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+ADD COLUMN suspended_at TIMESTAMP;
+
+-- hidden chirps stay in threads as a placeholder but are left out of every listing
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID NOT NULL,
+    -- the reported user, which for chirp reports is the author of the chirp
+    user_id UUID NOT NULL,
+    chirp_id UUID,
+    -- the body at the time of the report, so moderators see what was reported even after an edit
+    chirp_body TEXT,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    details TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+    assignee_id UUID,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (assignee_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at);
+-- a user can only have one open report about the same chirp or user
+CREATE UNIQUE INDEX reports_open_chirp_idx ON reports (reporter_id, chirp_id)
+WHERE status = 'open' AND chirp_id IS NOT NULL;
+CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, user_id)
+WHERE status = 'open' AND chirp_id IS NULL;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID,
+    action TEXT NOT NULL CHECK (action IN ('hide_chirp', 'remove_chirp', 'suspend_user', 'dismiss')),
+    report_id UUID,
+    user_id UUID,
+    chirp_id UUID,
+    note TEXT NOT NULL,
+    FOREIGN KEY (moderator_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE SET NULL
+);
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at);
+
+-- suspended users are hidden from everyone, like blocked ones
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION hidden_from(viewer_id UUID, author_id UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = author_id)
+            OR (blocks.blocker_id = author_id AND blocks.blocked_id = viewer_id)
+    ) OR EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = viewer_id AND mutes.muted_id = author_id
+    ) OR EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = author_id AND users.suspended_at IS NOT NULL
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- and so are rechirps of hidden chirps
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_hidden_from(viewer_id UUID, author_id UUID, rechirp_of UUID) RETURNS BOOLEAN AS $$
+    SELECT hidden_from(viewer_id, author_id) OR EXISTS (
+        SELECT 1 FROM chirps original
+        WHERE original.id = rechirp_of
+            AND (original.hidden_at IS NOT NULL OR hidden_from(viewer_id, original.user_id))
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_hidden_from(viewer_id UUID, author_id UUID, rechirp_of UUID) RETURNS BOOLEAN AS $$
+    SELECT hidden_from(viewer_id, author_id) OR EXISTS (
+        SELECT 1 FROM chirps original
+        WHERE original.id = rechirp_of AND hidden_from(viewer_id, original.user_id)
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION hidden_from(viewer_id UUID, author_id UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = author_id)
+            OR (blocks.blocker_id = author_id AND blocks.blocked_id = viewer_id)
+    ) OR EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = viewer_id AND mutes.muted_id = author_id
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
+ALTER TABLE users
+DROP COLUMN suspended_at,
+DROP COLUMN role;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- what admins did to users. no foreign keys, so the trail outlives both the admin and the user it is about
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_user_id UUID,
+    details JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_target_user_id_idx ON audit_events (target_user_id);
+
+-- +goose Down
+DROP TABLE audit_events;
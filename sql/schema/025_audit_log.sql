@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE audit_events
+ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id);
+CREATE INDEX audit_events_action_created_at_idx ON audit_events (action, created_at);
+
+-- the audit trail is append-only: rows can be added but never changed or removed, not even by chirpy itself
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only, % is not allowed', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update_delete
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+BEFORE TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TRIGGER audit_events_no_truncate ON audit_events;
+DROP TRIGGER audit_events_no_update_delete ON audit_events;
+DROP FUNCTION audit_events_append_only();
+DROP INDEX audit_events_action_created_at_idx;
+DROP INDEX audit_events_actor_id_idx;
+ALTER TABLE audit_events
+DROP COLUMN user_agent,
+DROP COLUMN ip;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- request counts per route, so they survive restarts. latencies and sizes only live in memory
+CREATE TABLE request_counts (
+    route TEXT NOT NULL,
+    method TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    count BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (route, method, status_code)
+);
+
+-- +goose Down
+DROP TABLE request_counts;
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/dcrauwels/chirpy/internal/pubsub"
+	"github.com/dcrauwels/chirpy/strutils"
+	"github.com/google/uuid"
+)
+
+// event types published on cfg.hub
+const (
+	eventChirpCreated = "chirp_created"
+	eventChirpDeleted = "chirp_deleted"
+	eventFollowed     = "followed"     // only used internally, see websocket.go
+	eventUnfollowed   = "unfollowed"   // idem
+	eventHideChanged  = "hide_changed" // idem and for GET /api/chirps/stream, on blocking, muting and changing muted words
+)
+
+const streamHeartbeat = 15 * time.Second
+
+// DeletedChirp is the data of a chirp_deleted event
+type DeletedChirp struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	hashtags []string  // the hashtags the chirp had, so hashtag subscribers hear about the deletion as well
+}
+
+// followChange is the data of followed and unfollowed events
+type followChange struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+// hideChange is the data of hide_changed events: a block or mute between the two users was added or removed,
+// or UserID changed their muted words, in which case OtherUserID is uuid.Nil
+type hideChange struct {
+	UserID      uuid.UUID
+	OtherUserID uuid.UUID
+}
+
+// publishChirp announces a new chirp to everyone listening. It is built as seen by an anonymous viewer,
+// as the same event goes out to all subscribers.
+func (cfg *apiConfig) publishChirp(ctx context.Context, chirp database.Chirp) {
+	responseChirp, err := cfg.buildChirp(ctx, chirp, uuid.Nil) // api.go
+	if err != nil {
+		log.Printf("error building chirp %s to publish: %s", chirp.ID, err)
+		return
+	}
+	cfg.hub.Publish(eventChirpCreated, responseChirp)
+}
+
+// publishChirpDeleted takes the chirp as it was before it was deleted
+func (cfg *apiConfig) publishChirpDeleted(chirp database.Chirp) {
+	cfg.hub.Publish(eventChirpDeleted, DeletedChirp{
+		ID:       chirp.ID,
+		UserID:   chirp.UserID,
+		hashtags: strutils.ExtractHashtags(chirp.Body),
+	})
+}
+
+// chirpEventUserID returns the author of the chirp in a chirp event, or false for any other event
+func chirpEventUserID(event pubsub.Event) (uuid.UUID, bool) {
+	switch data := event.Data.(type) {
+	case Chirp:
+		return data.UserID, true
+	case DeletedChirp:
+		return data.UserID, true
+	}
+	return uuid.Nil, false
+}
+
+// writeEvent writes an event in the Server-Sent Events format
+func writeEvent(w io.Writer, event pubsub.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+func (cfg *apiConfig) streamChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	//query parameters
+	authorID := uuid.Nil
+	if a := r.URL.Query().Get("author_id"); a != "" {
+		userID, err := uuid.Parse(a)
+		if err != nil {
+			writeError(w, 400, err, "invalid author ID provided")
+			return
+		}
+		authorID = userID
+	}
+	//browsers send this header themselves when an EventSource reconnects
+	var lastEventID uint64
+	if l := r.Header.Get("Last-Event-ID"); l != "" {
+		id, err := strconv.ParseUint(l, 10, 64)
+		if err != nil {
+			writeError(w, 400, err, "invalid Last-Event-ID header")
+			return
+		}
+		lastEventID = id
+	}
+
+	// the stream stays open far longer than the server's write timeout
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		writeError(w, 500, err, "streaming not supported")
+		return
+	}
+
+	// subscribe before writing anything, so no event is missed in between
+	sub, backlog := cfg.hub.Subscribe(lastEventID)
+	defer sub.Close()
+
+	// with an access token, the caller's blocks and mutes apply. loaded after subscribing, so a change in between
+	// still comes in as a hide_changed event
+	viewerID := cfg.optionalUserID(r) // auth.go
+	hidden := map[uuid.UUID]bool{}
+	stale := viewerID != uuid.Nil
+	loadHidden := func() error {
+		hiddenIDs, err := cfg.db.GetHiddenUserIDs(r.Context(), viewerID)
+		if err != nil {
+			return err
+		}
+		hidden = map[uuid.UUID]bool{}
+		for _, id := range hiddenIDs {
+			hidden[id] = true
+		}
+		stale = false
+		return nil
+	}
+	if stale {
+		err = loadHidden()
+		if err != nil {
+			writeError(w, 500, err, "error querying database")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keeps proxies like nginx from buffering the stream
+	w.WriteHeader(200)
+
+	// write events
+	send := func(event pubsub.Event) error {
+		switch data := event.Data.(type) {
+		case hideChange: // muted words do not apply here, so only changes between two users matter
+			stale = stale || (data.OtherUserID != uuid.Nil && (data.UserID == viewerID || data.OtherUserID == viewerID))
+		case Chirp:
+			if hidden[data.UserID] || (data.RechirpOf != nil && hidden[data.RechirpOf.UserID]) {
+				return nil
+			}
+		}
+		userID, ok := chirpEventUserID(event)
+		if !ok || (authorID != uuid.Nil && userID != authorID) {
+			return nil
+		}
+		return writeEvent(w, event)
+	}
+	for _, event := range backlog {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+	if stale && loadHidden() != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok { // dropped for being too slow. the client reconnects and resumes with Last-Event-ID
+				return
+			}
+			err = send(event)
+			if err == nil && stale {
+				err = loadHidden()
+			}
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n") // comment line, ignored by clients
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package strutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const maxHashtagLength = 100
+
+// ExtractHashtags returns the unique #hashtags in a chirp in order of appearance,
+// lowercased and without the leading '#'. Tags need at least one letter, so "#1" is not a hashtag.
+func ExtractHashtags(chirp string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	runes := []rune(chirp)
+	for i := 0; i < len(runes); i++ {
+		// a tag starts with a '#' that is not glued to the end of a word, like in "C#"
+		if runes[i] != '#' || (i > 0 && isWordRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		tag := strings.ToLower(string(runes[i+1 : end]))
+		if end-i-1 <= maxHashtagLength && strings.IndexFunc(tag, unicode.IsLetter) >= 0 && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		i = end - 1
+	}
+	return tags
+}
+
+// NormalizeHashtag turns user input like "#Chirpy" into the form tags are stored in
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+const (
+	minHandleLength = 3
+	maxHandleLength = 15
+)
+
+// Mention is an @handle in a chirp. Start and End are offsets in runes, End being exclusive,
+// and include the '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// ExtractMentions returns every @handle in a chirp in order of appearance, with its position.
+// Handles are not resolved to users here, so a mention is only valid if its handle exists.
+func ExtractMentions(chirp string) []Mention {
+	mentions := []Mention{}
+	runes := []rune(chirp)
+	for i := 0; i < len(runes); i++ {
+		// an '@' glued to the end of a word is part of an email address, not a mention
+		if runes[i] != '@' || (i > 0 && isWordRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		handle := string(runes[i+1 : end])
+		if ValidateHandle(handle) == nil {
+			mentions = append(mentions, Mention{Handle: handle, Start: i, End: end})
+		}
+		i = end - 1
+	}
+	return mentions
+}
+
+// ValidateHandle checks that a handle is 3 to 15 characters long and only consists of ASCII letters, digits and underscores
+func ValidateHandle(handle string) error {
+	if len(handle) < minHandleLength || len(handle) > maxHandleLength {
+		return fmt.Errorf("handle must be between %d and %d characters long", minHandleLength, maxHandleLength)
+	}
+	for _, r := range handle {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return errors.New("handle can only contain letters, digits and underscores")
+		}
+	}
+	return nil
+}
+
+// NormalizePhrase lowercases a phrase and collapses its whitespace, so "Foo  Bar" and "foo bar" are the same phrase
+func NormalizePhrase(phrase string) string {
+	return strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+}
+
+// ContainsPhrase reports whether text contains phrase as whole words, ignoring case and differences in whitespace.
+// "cat" matches "Cat!" but not "concatenate", and "#go" matches the hashtag but not "go" on its own.
+func ContainsPhrase(text, phrase string) bool {
+	target := []rune(NormalizePhrase(phrase))
+	if len(target) == 0 {
+		return false
+	}
+	runes := []rune(NormalizePhrase(text))
+	for i := 0; i+len(target) <= len(runes); i++ {
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+		end := i + len(target)
+		if end < len(runes) && isWordRune(runes[end]) {
+			continue
+		}
+		if string(runes[i:end]) == string(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package strutils
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FilterMask replaces every filtered word in Filter.Mask
+const FilterMask = "****"
+
+// leetRunes maps the symbols and digits commonly used to disguise letters to the letters they stand for
+var leetRunes = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// isPunctuationLeet reports whether r is a leet symbol that is far more often plain punctuation. These only
+// stand for a letter inside a word, so "hi!" is never read as "hii".
+func isPunctuationLeet(r rune) bool {
+	return r == '!' || r == '|'
+}
+
+// Filter finds the words of a word list in text, also when they are disguised: "Kerfuffle!", "KERFUFFLE",
+// "kérfuffle", "k3rfuffl3", "sh@rbert" and "fornaaax" all contain a listed word.
+// A Filter is never changed after NewFilter, so it is safe for concurrent use.
+type Filter struct {
+	words map[string][]listedWord // by skeleton
+	count int
+}
+
+// listedWord is a word of the list along with how often each letter of its skeleton repeats in it. Text has to
+// repeat them at least as often, so "fornaaax" matches "fornax" but "bob" does not match "boob".
+type listedWord struct {
+	word    string
+	repeats []int
+}
+
+// FilterMatch is a listed word found in a text. Start and End are offsets in runes, End being exclusive.
+type FilterMatch struct {
+	Word  string
+	Start int
+	End   int
+}
+
+// NewFilter returns a filter for the given words. Empty words are ignored.
+func NewFilter(words []string) *Filter {
+	f := &Filter{words: map[string][]listedWord{}}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		s, repeats := skeleton(word)
+		if s == "" || slices.ContainsFunc(f.words[s], func(w listedWord) bool { return w.word == word }) {
+			continue
+		}
+		f.words[s] = append(f.words[s], listedWord{word: word, repeats: repeats})
+		f.count++
+	}
+	return f
+}
+
+// Len returns the number of words in the filter
+func (f *Filter) Len() int {
+	return f.count
+}
+
+// skeleton reduces a word to the form it is compared in: lowercased, without accents, with look-alike
+// digits and symbols turned into letters, without underscores and with repeated letters collapsed into one.
+// repeats holds how many times each letter of the skeleton was repeated.
+func skeleton(word string) (string, []int) {
+	var b strings.Builder
+	repeats := []int{}
+	var last rune
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) || r == '_' {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if l, ok := leetRunes[r]; ok {
+			r = l
+		}
+		if r == last {
+			repeats[len(repeats)-1]++
+			continue
+		}
+		b.WriteRune(r)
+		repeats = append(repeats, 1)
+		last = r
+	}
+	return b.String(), repeats
+}
+
+// lookup returns the listed word token is a disguised form of
+func (f *Filter) lookup(token []rune) (string, bool) {
+	s, repeats := skeleton(string(token))
+	for _, w := range f.words[s] {
+		// equal skeletons have as many letters
+		matches := true
+		for i := range repeats {
+			matches = matches && repeats[i] >= w.repeats[i]
+		}
+		if matches {
+			return w.word, true
+		}
+	}
+	return "", false
+}
+
+func isTokenRune(r rune) bool {
+	_, leet := leetRunes[r]
+	return isWordRune(r) || leet
+}
+
+// Find returns the listed words in text in order of appearance. Text is split into words on anything that is
+// not a letter, digit or a symbol that could stand for one. Symbols at the edges of a word are tried both as part
+// of it and as punctuation, so "kerfuffle!" matches as "kerfuffle" followed by "!".
+func (f *Filter) Find(text string) []FilterMatch {
+	matches := []FilterMatch{}
+	if len(f.words) == 0 {
+		return matches
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if !isTokenRune(runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTokenRune(runes[end]) {
+			end++
+		}
+
+		// the whole token, but for symbols that are only letters inside a word
+		fullStart, fullEnd := i, end
+		for fullStart < fullEnd && isPunctuationLeet(runes[fullStart]) {
+			fullStart++
+		}
+		for fullEnd > fullStart && isPunctuationLeet(runes[fullEnd-1]) {
+			fullEnd--
+		}
+		// the token without any symbols at its edges
+		start, trimmedEnd := fullStart, fullEnd
+		for start < trimmedEnd && !isWordRune(runes[start]) {
+			start++
+		}
+		for trimmedEnd > start && !isWordRune(runes[trimmedEnd-1]) {
+			trimmedEnd--
+		}
+		if word, ok := f.lookup(runes[fullStart:fullEnd]); ok && fullStart < fullEnd {
+			matches = append(matches, FilterMatch{Word: word, Start: fullStart, End: fullEnd})
+		} else if word, ok := f.lookup(runes[start:trimmedEnd]); ok && start < trimmedEnd {
+			matches = append(matches, FilterMatch{Word: word, Start: start, End: trimmedEnd})
+		}
+		i = end - 1
+	}
+	return matches
+}
+
+// Mask returns text with every listed word replaced by FilterMask, and the words that were replaced
+func (f *Filter) Mask(text string) (string, []FilterMatch) {
+	matches := f.Find(text)
+	if len(matches) == 0 {
+		return text, matches
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(string(runes[last:m.Start]))
+		b.WriteString(FilterMask)
+		last = m.End
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String(), matches
+}
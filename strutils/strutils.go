@@ -3,32 +3,81 @@ package strutils
 import (
 	"fmt"
 	"net/mail"
-	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
 )
 
-func ChirpLength(chirp string, maxLength int) error {
-	if len(chirp) > maxLength {
-		return fmt.Errorf("chirp exceeds maximum length of %d characters", maxLength)
-	}
-	return nil
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// URLLength is what every link in a chirp counts for, however long it is
+const URLLength = 23
+
+// NormalizeChirp puts a chirp in NFC form, so an accented letter is stored and counted the same way
+// whether it was typed as one code point or as a letter followed by a combining accent
+func NormalizeChirp(chirp string) string {
+	return norm.NFC.String(chirp)
 }
 
-func ReplaceWord(input string, target []string, fixed string) string {
-	slicedInput := strings.Split(input, " ")
-	fixedInput := make([]string, len(slicedInput))
-	for i, w := range slicedInput {
-		if slices.Contains(target, strings.ToLower(w)) {
-			fixedInput[i] = fixed
+// ChirpLength returns the length of a chirp in user-perceived characters (grapheme clusters), so an emoji
+// like a family or a flag counts as one character. Links starting with http:// or https:// count as URLLength.
+func ChirpLength(chirp string) int {
+	length := 0
+	for i, field := range splitKeepSpace(chirp) {
+		if i%2 == 1 && (strings.HasPrefix(field, "http://") || strings.HasPrefix(field, "https://")) {
+			length += URLLength
 		} else {
-			fixedInput[i] = w
+			length += uniseg.GraphemeClusterCount(field)
+		}
+	}
+	return length
+}
+
+// splitKeepSpace splits s into alternating runs of whitespace and non-whitespace, starting with
+// a (possibly empty) run of whitespace, so joining the parts gives back s
+func splitKeepSpace(s string) []string {
+	parts := []string{}
+	start := 0
+	inSpace := true
+	for i, r := range s {
+		if unicode.IsSpace(r) != inSpace {
+			parts = append(parts, s[start:i])
+			start = i
+			inSpace = !inSpace
 		}
 	}
-	output := strings.Join(fixedInput, " ")
-	return output
+	return append(parts, s[start:])
 }
 
 func ValidateEmail(email string) error {
 	_, err := mail.ParseAddress(email)
 	return err
 }
+
+func ValidateDisplayName(name string) error {
+	return validateProfileText("display name", name, maxDisplayNameLength, false)
+}
+
+func ValidateBio(bio string) error {
+	return validateProfileText("bio", bio, maxBioLength, true)
+}
+
+// validateProfileText checks the length of free text on a profile and rejects control characters,
+// except for newlines if allowNewlines is set
+func validateProfileText(field, text string, maxLength int, allowNewlines bool) error {
+	if utf8.RuneCountInString(text) > maxLength {
+		return fmt.Errorf("%s cannot exceed %d characters", field, maxLength)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) && !(allowNewlines && r == '\n') {
+			return fmt.Errorf("%s cannot contain control characters", field)
+		}
+	}
+	return nil
+}
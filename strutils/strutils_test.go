@@ -0,0 +1,145 @@
+package strutils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	cases := []struct {
+		chirp    string
+		expected []string
+	}{
+		{"no tags here", []string{}},
+		{"#Go is #fun", []string{"go", "fun"}},
+		{"#go and #GO again", []string{"go"}},
+		{"trailing punctuation #chirpy! and #tags, work", []string{"chirpy", "tags"}},
+		{"not a tag: C# or #1", []string{}},
+		{"unicode #café #東京", []string{"café", "東京"}},
+		{"#snake_case", []string{"snake_case"}},
+	}
+
+	for _, c := range cases {
+		actual := ExtractHashtags(c.chirp)
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf(`ExtractHashtags(%q) = %v; expected %v`, c.chirp, actual, c.expected)
+		}
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	cases := []struct {
+		chirp    string
+		expected []Mention
+	}{
+		{"no mentions", []Mention{}},
+		{"@alice hi", []Mention{{Handle: "alice", Start: 0, End: 6}}},
+		{"héllo @bob_1, @Carol!", []Mention{{Handle: "bob_1", Start: 6, End: 12}, {Handle: "Carol", Start: 14, End: 20}}},
+		{"mail me at me@example.com", []Mention{}},
+		{"@x is too short and @waytoolonghandle_x is too long", []Mention{}},
+	}
+
+	for _, c := range cases {
+		actual := ExtractMentions(c.chirp)
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf(`ExtractMentions(%q) = %v; expected %v`, c.chirp, actual, c.expected)
+		}
+	}
+}
+
+func TestContainsPhrase(t *testing.T) {
+	cases := []struct {
+		text     string
+		phrase   string
+		expected bool
+	}{
+		{"I love my cat", "cat", true},
+		{"CAT!", "cat", true},
+		{"concatenate", "cat", false},
+		{"cats", "cat", false},
+		{"spoilers for  the\nfinale", "The Finale", true},
+		{"the finale", "the final", false},
+		{"learning #Go today", "#go", true},
+		{"learning go today", "#go", false},
+		{"C#go", "#go", false},
+		{"anything", "   ", false},
+	}
+
+	for _, c := range cases {
+		actual := ContainsPhrase(c.text, c.phrase)
+		if actual != c.expected {
+			t.Errorf(`ContainsPhrase(%q, %q) = %v; expected %v`, c.text, c.phrase, actual, c.expected)
+		}
+	}
+}
+
+func TestFilterMask(t *testing.T) {
+	f := NewFilter([]string{"kerfuffle", "sharbert", "fornax"})
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"This is a kerfuffle opinion I need to share with the world", "This is a **** opinion I need to share with the world"},
+		{"Kerfuffle! what a KERFUFFLE, really", "****! what a ****, really"},
+		{"tabs\tkerfuffle\tand\nnewlines", "tabs\t****\tand\nnewlines"},
+		{"k3rfuffl3 and sh@rbert", "**** and ****"},
+		{"fornaaaax and kérfuffle", "**** and ****"},
+		{"(fornax)", "(****)"},
+		{"kerfuffled and fornaxes are fine", "kerfuffled and fornaxes are fine"},
+		{"nothing to see here", "nothing to see here"},
+	}
+
+	for _, c := range cases {
+		actual, _ := f.Mask(c.input)
+		if actual != c.expected {
+			t.Errorf(`Mask(%q) = %q; expected %q`, c.input, actual, c.expected)
+		}
+	}
+}
+
+func TestFilterRepeatedLetters(t *testing.T) {
+	// letters doubled in a listed word have to be at least doubled in the text
+	f := NewFilter([]string{"boob", "ass", "hii"})
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"Bob is here", "Bob is here"},
+		{"as good as", "as good as"},
+		{"boob and booooob", "**** and ****"},
+		{"ass, a$$ and aaasss", "****, **** and ****"},
+		{"hi! and hi!!", "hi! and hi!!"}, // exclamation marks at the end of a word are punctuation
+		{"h!i and h1i", "**** and ****"},
+	}
+
+	for _, c := range cases {
+		actual, _ := f.Mask(c.input)
+		if actual != c.expected {
+			t.Errorf(`Mask(%q) = %q; expected %q`, c.input, actual, c.expected)
+		}
+	}
+}
+
+func TestChirpLength(t *testing.T) {
+	cases := []struct {
+		chirp    string
+		expected int
+	}{
+		{"", 0},
+		{"hello world", 11},
+		{"héllo", 5},
+		{"e\u0301", 1}, // e followed by a combining accent
+		{"👍👍👍", 3},
+		{"👨‍👩‍👧‍👦 and 🇳🇱", 7},
+		{"look: https://example.com/a/very/long/path/that/goes/on/and/on", 6 + URLLength},
+		{"http://x.y", URLLength},
+		{"nohttps://example.com", 21},
+	}
+
+	for _, c := range cases {
+		actual := ChirpLength(c.chirp)
+		if actual != c.expected {
+			t.Errorf(`ChirpLength(%q) = %d; expected %d`, c.chirp, actual, c.expected)
+		}
+	}
+}
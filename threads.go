@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/dcrauwels/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxThreadDepth   int32 = 50  // how many levels of replies are walked up and down from a chirp
+	maxThreadReplies int32 = 500 // how many replies below a chirp are returned at most
+)
+
+// threadNode is a chirp with its replies nested below it
+type threadNode struct {
+	Chirp
+	Replies []*threadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) getThreadHandler(w http.ResponseWriter, r *http.Request) {
+	// read request
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, err, "endpoint is not a valid uuid")
+		return
+	}
+
+	// query DB
+	//the chirp itself. unlike GET /api/chirps/{chirpID} this also works for tombstones
+	chirp, err := cfg.db.GetSingleChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "chirp not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	viewerID := cfg.optionalUserID(r) // auth.go
+	if !cfg.requireVisibleChirp(w, r, chirp, viewerID) {
+		return
+	}
+	//everything above it
+	ancestors, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ChirpID:  chirpID,
+		ViewerID: viewerID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database for parent chirps")
+		return
+	}
+	//everything below it
+	descendants, err := cfg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ChirpID:    chirpID,
+		ViewerID:   viewerID,
+		MaxDepth:   maxThreadDepth,
+		MaxReplies: maxThreadReplies,
+	})
+	if err != nil {
+		writeError(w, 500, err, "error querying database for replies")
+		return
+	}
+
+	// build response
+	//one go for all chirps so reply counts etc. are looked up together
+	all := append(append(ancestors, chirp), descendants...)
+	responseChirps, err := cfg.buildChirps(r.Context(), all, viewerID)
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseChirps, err = cfg.collapseMutedWords(r.Context(), responseChirps, viewerID) // mutedwords.go
+	if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
+	responseAncestors := responseChirps[:len(ancestors)]
+	root := &threadNode{Chirp: responseChirps[len(ancestors)], Replies: []*threadNode{}}
+
+	//descendants are sorted oldest first, so a reply's parent is always in the tree before the reply is
+	nodes := map[uuid.UUID]*threadNode{root.ID: root}
+	for _, reply := range responseChirps[len(ancestors)+1:] {
+		if reply.InReplyTo == nil {
+			continue
+		}
+		parent, ok := nodes[*reply.InReplyTo]
+		if !ok {
+			continue
+		}
+		node := &threadNode{Chirp: reply, Replies: []*threadNode{}}
+		parent.Replies = append(parent.Replies, node)
+		nodes[node.ID] = node
+	}
+
+	// write response
+	respParams := struct {
+		Ancestors []Chirp     `json:"ancestors"`
+		Chirp     *threadNode `json:"chirp"`
+	}{
+		Ancestors: responseAncestors,
+		Chirp:     root,
+	}
+	writeJSON(w, 200, respParams)
+}
@@ -44,6 +44,15 @@ func (cfg *apiConfig) polkaHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// if that check passed then event == user.upgraded -> we upgrade user
+	//webhooks can be delivered more than once, so only notify users that weren't upgraded yet
+	user, err := cfg.db.GetUserByID(r.Context(), reqParams.Data.UserID)
+	if err == sql.ErrNoRows {
+		writeError(w, 404, err, "user not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err, "error querying database")
+		return
+	}
 	//query SetChirpyRedByID
 	_, err = cfg.db.SetChirpyRedByID(r.Context(), reqParams.Data.UserID)
 	//err == sql.ErrNoRows > 404
@@ -54,6 +63,10 @@ func (cfg *apiConfig) polkaHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, 500, err, "error querying database")
 		return
 	}
+	if !user.IsChirpyRed {
+		cfg.notify(r.Context(), user.ID, uuid.Nil, notificationChirpyRed, uuid.NullUUID{})             // notifications.go
+		cfg.audit(r, uuid.Nil, auditChirpyRedPolka, user.ID, map[string]any{"event": reqParams.Event}) // audit.go
+	}
 
 	// return 204 > u get out
 	writeJSON(w, 204, nil)
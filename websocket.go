@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dcrauwels/chirpy/internal/auth"
+	"github.com/dcrauwels/chirpy/internal/pubsub"
+	"github.com/dcrauwels/chirpy/strutils"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second // a client that does not take a frame within this time is disconnected
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = 30 * time.Second // has to be shorter than wsPongWait
+	wsMaxMessageSize = 4096
+	wsMaxTopics      = 50
+	wsAuthWait       = 10 * time.Second // for the auth frame of clients that cannot send an Authorization header
+	// close codes from 4000 up are free for applications
+	wsCloseTokenExpired = 4001
+	wsCloseUnauthorized = 4003
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsClientMessage is a frame sent by the client
+type wsClientMessage struct {
+	Type  string `json:"type"` // subscribe, unsubscribe or auth
+	Topic string `json:"topic"`
+	Token string `json:"token"` // the access token in auth messages: the first one, or a new one to stay connected after it expires
+}
+
+// wsServerMessage is a frame sent to the client
+type wsServerMessage struct {
+	Type   string   `json:"type"`
+	Topic  string   `json:"topic,omitempty"`  // for replies to subscribe and unsubscribe
+	Topics []string `json:"topics,omitempty"` // for events: every subscribed topic the event belongs to
+	Data   any      `json:"data,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// wsSession is the state of a single connection. Only the handler goroutine touches it.
+type wsSession struct {
+	userID     uuid.UUID
+	expiresAt  time.Time
+	topics     map[string]bool
+	following  map[uuid.UUID]bool // loaded when subscribing to home, then kept up to date through follow events
+	hidden     map[uuid.UUID]bool // users who blocked or were blocked or muted by the user, see blocks.go
+	mutedWords mutedWords         // see mutedwords.go
+	stale      bool               // hidden and mutedWords have to be reloaded
+}
+
+// parseTopic checks a topic and returns it in its normal form. Topics are "home", "notifications",
+// "user:{userID}" and "hashtag:{tag}".
+func parseTopic(topic string) (string, error) {
+	kind, arg, _ := strings.Cut(topic, ":")
+	switch kind {
+	case "home", "notifications":
+		if arg == "" {
+			return kind, nil
+		}
+	case "user":
+		userID, err := uuid.Parse(arg)
+		if err == nil {
+			return "user:" + userID.String(), nil
+		}
+	case "hashtag":
+		if tag := strutils.NormalizeHashtag(arg); tag != "" {
+			return "hashtag:" + tag, nil
+		}
+	}
+	return "", fmt.Errorf("unknown topic %q", topic)
+}
+
+// chirpTopics returns the subscribed topics a chirp event belongs to
+func (s *wsSession) chirpTopics(userID uuid.UUID, hashtags []string) []string {
+	topics := []string{}
+	if s.topics["home"] && s.following[userID] {
+		topics = append(topics, "home")
+	}
+	if topic := "user:" + userID.String(); s.topics[topic] {
+		topics = append(topics, topic)
+	}
+	for _, tag := range hashtags {
+		if topic := "hashtag:" + tag; s.topics[topic] {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// handleEvent turns an event from the hub into the frames for this client, if any
+func (s *wsSession) handleEvent(event pubsub.Event) []wsServerMessage {
+	frames := []wsServerMessage{}
+	switch data := event.Data.(type) {
+	case Chirp:
+		if s.hidden[data.UserID] || (data.RechirpOf != nil && s.hidden[data.RechirpOf.UserID]) {
+			break
+		}
+		phrases, hide := s.mutedWords.match(data)
+		if hide {
+			break
+		}
+		data.MutedWords = phrases
+		if topics := s.chirpTopics(data.UserID, strutils.ExtractHashtags(data.Body)); len(topics) > 0 {
+			frames = append(frames, wsServerMessage{Type: event.Type, Topics: topics, Data: data})
+		}
+	case DeletedChirp:
+		if topics := s.chirpTopics(data.UserID, data.hashtags); len(topics) > 0 {
+			frames = append(frames, wsServerMessage{Type: event.Type, Topics: topics, Data: data})
+		}
+	case Notification:
+		if s.topics["notifications"] && data.userID == s.userID {
+			frames = append(frames, wsServerMessage{Type: event.Type, Topics: []string{"notifications"}, Data: data})
+		}
+	case followChange:
+		if data.FollowerID == s.userID && s.following != nil {
+			s.following[data.FolloweeID] = event.Type == eventFollowed
+		}
+	case hideChange:
+		s.stale = s.stale || data.UserID == s.userID || data.OtherUserID == s.userID
+	}
+	return frames
+}
+
+// loadHidden (re)loads the users and muted words whose chirps the client should not get
+func (cfg *apiConfig) loadHidden(ctx context.Context, s *wsSession) error {
+	hiddenIDs, err := cfg.db.GetHiddenUserIDs(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+	s.mutedWords, err = cfg.loadMutedWords(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+	s.hidden = map[uuid.UUID]bool{}
+	for _, id := range hiddenIDs {
+		s.hidden[id] = true
+	}
+	s.stale = false
+	return nil
+}
+
+// handleWSMessage handles a frame from the client and returns the reply
+func (cfg *apiConfig) handleWSMessage(ctx context.Context, s *wsSession, raw []byte) wsServerMessage {
+	msg := wsClientMessage{}
+	err := json.Unmarshal(raw, &msg)
+	if err != nil {
+		return wsServerMessage{Type: "error", Error: "message has incorrect JSON structure"}
+	}
+
+	switch msg.Type {
+	case "subscribe", "unsubscribe":
+		topic, err := parseTopic(msg.Topic)
+		if err != nil {
+			return wsServerMessage{Type: "error", Topic: msg.Topic, Error: err.Error()}
+		}
+		if msg.Type == "unsubscribe" {
+			delete(s.topics, topic)
+			return wsServerMessage{Type: "unsubscribed", Topic: topic}
+		}
+		if len(s.topics) >= wsMaxTopics && !s.topics[topic] {
+			return wsServerMessage{Type: "error", Topic: topic, Error: fmt.Sprintf("cannot subscribe to more than %d topics", wsMaxTopics)}
+		}
+		if topic == "home" && s.following == nil {
+			followeeIDs, err := cfg.db.GetFolloweeIDs(ctx, s.userID)
+			if err != nil {
+				return wsServerMessage{Type: "error", Topic: topic, Error: "error querying database"}
+			}
+			s.following = map[uuid.UUID]bool{}
+			for _, id := range followeeIDs {
+				s.following[id] = true
+			}
+		}
+		s.topics[topic] = true
+		return wsServerMessage{Type: "subscribed", Topic: topic}
+
+	case "auth":
+		userID, expiresAt, err := auth.ValidateJWTExpiry(msg.Token, cfg.secret)
+		if err != nil || userID != s.userID {
+			return wsServerMessage{Type: "error", Error: "invalid token"}
+		}
+		s.expiresAt = expiresAt
+		return wsServerMessage{Type: "authenticated"}
+	}
+	return wsServerMessage{Type: "error", Error: fmt.Sprintf("unknown message type %q", msg.Type)}
+}
+
+// wsAuthenticate waits for the first frame of a client that connected without an Authorization header,
+// which has to be an auth message with its access token
+func (cfg *apiConfig) wsAuthenticate(conn *websocket.Conn) (uuid.UUID, time.Time, error) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsAuthWait))
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		return uuid.Nil, time.Time{}, errors.New("no auth message")
+	}
+	msg := wsClientMessage{}
+	err = json.Unmarshal(raw, &msg)
+	if err != nil || msg.Type != "auth" {
+		return uuid.Nil, time.Time{}, errors.New("first message has to be auth")
+	}
+	userID, expiresAt, err := auth.ValidateJWTExpiry(msg.Token, cfg.secret)
+	if err != nil {
+		return uuid.Nil, time.Time{}, errors.New("invalid token")
+	}
+	return userID, expiresAt, nil
+}
+
+func (cfg *apiConfig) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	// tokenomics. browsers cannot set headers on websocket requests, so they send the token in an auth frame right
+	// after connecting instead. never in the URL, which ends up in access and proxy logs
+	var userID uuid.UUID
+	var expiresAt time.Time
+	token, err := auth.GetBearerToken(r.Header)
+	if err == nil {
+		userID, expiresAt, err = auth.ValidateJWTExpiry(token, cfg.secret)
+		if err != nil {
+			writeError(w, 401, err, "user not authorized")
+			return
+		}
+	}
+
+	// upgrade. on failure the upgrader has already responded
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	closeWith := func(code int, text string) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait))
+	}
+	write := func(msg wsServerMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(msg)
+	}
+
+	if userID == uuid.Nil {
+		userID, expiresAt, err = cfg.wsAuthenticate(conn)
+		if err != nil {
+			closeWith(wsCloseUnauthorized, err.Error())
+			return
+		}
+		if err = write(wsServerMessage{Type: "authenticated"}); err != nil {
+			return
+		}
+	}
+	session := &wsSession{userID: userID, expiresAt: expiresAt, topics: map[string]bool{}}
+	err = cfg.loadHidden(r.Context(), session)
+	if err != nil {
+		closeWith(websocket.CloseInternalServerErr, "error querying database")
+		return
+	}
+	sub, _ := cfg.hub.Subscribe(0) // stream.go
+	defer sub.Close()
+
+	// the reader hands frames to this goroutine, which is the only one writing to the connection
+	messages := make(chan []byte)
+	readerDone := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(readerDone)
+		conn.SetReadLimit(wsMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			select {
+			case messages <- raw:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	expiry := time.NewTimer(time.Until(session.expiresAt))
+	defer expiry.Stop()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-readerDone:
+			return
+		case raw := <-messages:
+			err = write(cfg.handleWSMessage(r.Context(), session, raw))
+			expiry.Reset(time.Until(session.expiresAt)) // an auth message moves the expiry
+		case event, ok := <-sub.Events():
+			if !ok {
+				closeWith(websocket.CloseTryAgainLater, "client too slow")
+				return
+			}
+			for _, frame := range session.handleEvent(event) {
+				if err = write(frame); err != nil {
+					break
+				}
+			}
+			if err == nil && session.stale {
+				err = cfg.loadHidden(r.Context(), session)
+			}
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case <-expiry.C:
+			closeWith(wsCloseTokenExpired, "token expired")
+			return
+		}
+		if err != nil { // most likely a client that stopped reading
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dcrauwels/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+func TestParseTopic(t *testing.T) {
+	userID := uuid.New()
+	cases := []struct {
+		topic    string
+		expected string
+		valid    bool
+	}{
+		{"home", "home", true},
+		{"notifications", "notifications", true},
+		{"user:" + userID.String(), "user:" + userID.String(), true},
+		{"user:" + strings.ToUpper(userID.String()), "user:" + userID.String(), true},
+		{"hashtag:Go", "hashtag:go", true},
+		{"hashtag:#chirpy", "hashtag:chirpy", true},
+		{"home:extra", "", false},
+		{"user:not-a-uuid", "", false},
+		{"hashtag:", "", false},
+		{"everything", "", false},
+		{"", "", false},
+	}
+
+	for _, c := range cases {
+		actual, err := parseTopic(c.topic)
+		if c.valid && (err != nil || actual != c.expected) {
+			t.Errorf(`parseTopic(%q) = %q, %v; expected %q`, c.topic, actual, err, c.expected)
+		} else if !c.valid && err == nil {
+			t.Errorf(`parseTopic(%q) = %q; expected an error`, c.topic, actual)
+		}
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	me, followee, stranger, blocked := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	s := &wsSession{
+		userID:     me,
+		topics:     map[string]bool{"home": true, "hashtag:go": true, "notifications": true},
+		following:  map[uuid.UUID]bool{followee: true},
+		hidden:     map[uuid.UUID]bool{blocked: true},
+		mutedWords: mutedWords{{Phrase: "spoiler", Action: mutedWordHide}, {Phrase: "meh", Action: mutedWordCollapse}},
+	}
+	topicsOf := func(frames []wsServerMessage) [][]string {
+		topics := [][]string{}
+		for _, frame := range frames {
+			topics = append(topics, frame.Topics)
+		}
+		return topics
+	}
+
+	cases := []struct {
+		name     string
+		event    pubsub.Event
+		expected [][]string
+	}{
+		{"followee", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "hello"}}, [][]string{{"home"}}},
+		{"followee with hashtag", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "#Go rocks"}}, [][]string{{"home", "hashtag:go"}}},
+		{"stranger", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: stranger, Body: "hello"}}, [][]string{}},
+		{"stranger with hashtag", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: stranger, Body: "#go"}}, [][]string{{"hashtag:go"}}},
+		{"blocked", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: blocked, Body: "#go"}}, [][]string{}},
+		{"rechirp of blocked", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, RechirpOf: &Chirp{UserID: blocked}}}, [][]string{}},
+		{"muted hide", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "big spoiler"}}, [][]string{}},
+		{"quoting muted", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "look", QuotedChirp: &Chirp{Body: "spoiler!"}}}, [][]string{}},
+		{"rechirp of quoting muted", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, RechirpOf: &Chirp{Body: "look", QuotedChirp: &Chirp{Body: "spoiler"}}}}, [][]string{}},
+		{"muted collapse", pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "meh"}}, [][]string{{"home"}}},
+		{"deleted", pubsub.Event{Type: eventChirpDeleted, Data: DeletedChirp{UserID: stranger, hashtags: []string{"go"}}}, [][]string{{"hashtag:go"}}},
+		{"my notification", pubsub.Event{Type: eventNotification, Data: Notification{userID: me}}, [][]string{{"notifications"}}},
+		{"someone else's notification", pubsub.Event{Type: eventNotification, Data: Notification{userID: stranger}}, [][]string{}},
+	}
+	for _, c := range cases {
+		frames := s.handleEvent(c.event)
+		if actual := topicsOf(frames); !slices.EqualFunc(actual, c.expected, slices.Equal) {
+			t.Errorf(`handleEvent(%s) topics = %v; expected %v`, c.name, actual, c.expected)
+		}
+	}
+
+	// collapsed chirps go out with the phrases they matched
+	frames := s.handleEvent(pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: followee, Body: "meh"}})
+	if chirp := frames[0].Data.(Chirp); !slices.Equal(chirp.MutedWords, []string{"meh"}) {
+		t.Errorf(`handleEvent() muted_words = %v; expected [meh]`, chirp.MutedWords)
+	}
+
+	// follow events keep the home topic up to date
+	s.handleEvent(pubsub.Event{Type: eventFollowed, Data: followChange{FollowerID: me, FolloweeID: stranger}})
+	if frames := s.handleEvent(pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: stranger, Body: "hi"}}); len(frames) != 1 {
+		t.Errorf(`handleEvent() after following sent %d frames; expected 1`, len(frames))
+	}
+	s.handleEvent(pubsub.Event{Type: eventUnfollowed, Data: followChange{FollowerID: me, FolloweeID: stranger}})
+	if frames := s.handleEvent(pubsub.Event{Type: eventChirpCreated, Data: Chirp{UserID: stranger, Body: "hi"}}); len(frames) != 0 {
+		t.Errorf(`handleEvent() after unfollowing sent %d frames; expected none`, len(frames))
+	}
+
+	// blocks and mutes involving someone else leave the session alone
+	s.handleEvent(pubsub.Event{Type: eventHideChanged, Data: hideChange{UserID: stranger, OtherUserID: followee}})
+	if s.stale {
+		t.Errorf(`handleEvent() made the session stale for a change between other users`)
+	}
+	s.handleEvent(pubsub.Event{Type: eventHideChanged, Data: hideChange{UserID: stranger, OtherUserID: me}})
+	if !s.stale {
+		t.Errorf(`handleEvent() did not make the session stale for a block of the user`)
+	}
+}